@@ -21,64 +21,217 @@
 package antlabs
 
 import (
+	"bufio"
+	"context"
+	"io"
 	"net/http"
+	neturl "net/url"
 	"crypto/tls"
 	// "fmt"
 	"strconv"
 	"io/ioutil"
-	"regexp"
-	// "strings"
+	"strings"
 	"errors"
 )
 
-type Host struct{
-	Host      string
-	Port      int
-	ApiPass   string
-	AdminPass string
-	AdminName string
+//Field is one "name = value" line of a reply from the API.
+type Field struct{
+	Line  int //line of the reply body, counting from 1
+	Name  string
+	Value string
 }
 
-func basicURL(url string) (body []byte, err error){
-	tr := &http.Transport{ TLSClientConfig: &tls.Config{InsecureSkipVerify : true} }
-	client := &http.Client{Transport: tr}
-	resp, err := client.Get(url)
+//ParseError reports a reply that could not be decoded.  Line and Field
+//identify where in the reply the problem is, when that is known.
+type ParseError struct{
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() (string){
+	msg := "cannot parse reply"
+	if(e.Line > 0){ msg += " line " + strconv.Itoa(e.Line) }
+	if(e.Field != ""){ msg += " (" + e.Field + ")" }
+	if(e.Err != nil){ msg += ": " + e.Err.Error() }
+	return msg
+}
+
+func (e *ParseError) Unwrap() (error){ return e.Err }
+
+//StatusError reports a reply with an HTTP status other than 2xx, e.g. a 404
+//from a wrong API path or a 500 from the gateway's web server; its body is
+//not read.
+type StatusError struct{
+	StatusCode int
+	Status     string //e.g. "404 Not Found"
+}
+
+func (e *StatusError) Error() (string){ return "HTTP status " + e.Status }
+
+//insecureClient is used when no *http.Client is given.  It ignores the
+//certificate because a gateway's certificate is self-signed to ezxcess.antlabs.com.
+var insecureClient = &http.Client{
+	Transport : &http.Transport{ TLSClientConfig : &tls.Config{InsecureSkipVerify : true} },
+}
+
+func basicURL(ctx context.Context, client *http.Client, url string) (body []byte, err error){
+	stream, err := openURL(ctx, client, url)
 	if(err != nil){return nil, err}
+	defer stream.Close()
 	
-	body, err = ioutil.ReadAll(resp.Body)
+	body, err = ioutil.ReadAll(stream)
 	if(err != nil){return nil, err}
-	defer resp.Body.Close()
 	
 	return body, nil
 }
 
-//InnGateAPIRequest takes a querystring as the only argument and returns a map of values.
+//InnGateAPIRequest fetches url, the API address followed by a querystring, with
+//client (nil for a client that accepts the gateway's self-signed certificate) and
+//returns the fields of the reply.  ctx bounds the request.  Errors never contain
+//the querystring, which carries the api_password.
 //All ANTLabs InnGate API requests work by a very simple webservice.  A URL is crafted
 //according to the API to make the proper request.  The result is a plain-text file with
 //lines that look like:
 //field = value
 //when a field has multiple values, they'll be delimited by pipes:
 //field = value1|value2|value3|...
-//The map of fields to values is produced by processing the body in parseBody().
-func (ant *Host) InnGateApiRequest(queryString string) (parsed_body [][]string, err error){
-	//we must ignore the certificate because it is self-signed to ezxcess.antlabs.com
-	body, err := basicURL("https://"+ant.Host+":"+strconv.Itoa(ant.Port)+"/api/?"+queryString)
+//The list of fields and values is produced by processing the body in ParseApiResponse().
+func InnGateApiRequest(ctx context.Context, client *http.Client, url string) (fields []Field, err error){
+	body, err := basicURL(ctx, client, url)
 	if(err != nil){return nil, err}
 	
+	return ParseApiResponse(string(body))
+}
+
+//InnGateApiFetch fetches url as InnGateApiRequest does, but returns the body of the
+//reply unparsed.  Like InnGateApiRequest, its errors never contain the querystring
+//(which carries the api_password).
+func InnGateApiFetch(ctx context.Context, client *http.Client, url string) (body []byte, err error){
+	return basicURL(ctx, client, url)
+}
+
+//InnGateApiStream fetches url as InnGateApiFetch does, but returns the body of
+//the reply as it arrives, to be read with a FieldScanner; the caller must
+//close it.
+func InnGateApiStream(ctx context.Context, client *http.Client, url string) (body io.ReadCloser, err error){
+	return openURL(ctx, client, url)
+}
+
+func openURL(ctx context.Context, client *http.Client, url string) (body io.ReadCloser, err error){
+	if(client == nil){ client = insecureClient }
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if(err != nil){return nil, scrub(err)}
+	resp, err := client.Do(req)
+	if(err != nil){return nil, scrub(err)}
+	if(resp.StatusCode < 200 || resp.StatusCode > 299){
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode : resp.StatusCode, Status : resp.Status}
+	}
+	return resp.Body, nil
+}
+
+//scrub removes the querystring, and with it the api_password, from the URL that
+//net/http puts in its errors.
+func scrub(err error) (error){
+	uerr, ok := err.(*neturl.Error)
+	if(!ok){ return err }
+	scrubbed := *uerr
+	if i := strings.IndexByte(scrubbed.URL, '?'); i >= 0{ scrubbed.URL = scrubbed.URL[:i] }
+	return &scrubbed
+}
+
+//ParseApiResponse converts the plain-text response from the API into a list of fields and values.
+//Blank lines are skipped, and a blank value ("vlan = ") is an empty string.  Any other line that
+//is not a name, an equals sign and a value is reported as a *ParseError; so is a body with no
+//fields at all.  Data verification is performed elsewhere.
+func ParseApiResponse(body string) (fields []Field, err error){
+	for i, line := range strings.Split(body, "\n"){
+		field, ok, err := parseLine(i+1, line)
+		if(err != nil){ return nil, err }
+		if(ok){ fields = append(fields, field) }
+	}
+	if(len(fields) == 0){ return nil, errEmpty() }
+	return fields, nil
+}
+
+func errEmpty() (error){ return &ParseError{Err : errors.New("Failed to parse body; 0 length")} }
+
+//parseLine parses line n of a reply; ok is false for a blank line.
+func parseLine(n int, line string) (field Field, ok bool, err error){
+	line = strings.TrimSpace(line)
+	if(line == ""){ return Field{}, false, nil }
 	
-	parsed_body, err = parseApiResponse(string(body))
-	if(err != nil){return nil, err}
+	eq := strings.IndexByte(line, '=')
+	if(eq < 0){ return Field{}, false, &ParseError{Line : n, Err : errors.New("expected a line of the form name = value")} }
+	name := strings.TrimSpace(line[:eq])
+	if(!validFieldName(name)){ return Field{}, false, &ParseError{Line : n, Err : errors.New("invalid field name " + strconv.Quote(name))} }
 	
-	return parsed_body, nil
-}
-
-//parseApiResponse is called by APIRequest and converts the plain-text response from the API into a map of fields and values.
-//This is done using a very simple regular expression; data verification is performed elsewhere.
-func parseApiResponse(body string) (matches [][]string, err error){
-	assigned := regexp.MustCompile(`(?m)^(?:\s*)(\w+?)(?:\s*)=(?:\s*)(.+?)(?:\s*)$`)
-	matches   = assigned.FindAllStringSubmatch(body, -1)
-	err = nil
-	//fmt.Println(body)
-	if(len(matches) == 0){return nil, errors.New("Failed to parse body; 0 length")}
-	return
-}
\ No newline at end of file
+	return Field{Line : n, Name : name, Value : strings.TrimSpace(line[eq+1:])}, true, nil
+}
+
+//FieldScanner reads the fields of a reply one at a time as the body arrives,
+//with the same rules as ParseApiResponse, so a long reply (account_get_all)
+//need not be held in memory.
+//
+//Example:
+//  scanner := antlabs.NewFieldScanner(body)
+//  for scanner.Scan(){ fmt.Println(scanner.Field().Name) }
+//  if err := scanner.Err(); err != nil{ panic(err) }
+type FieldScanner struct{
+	lines  *bufio.Scanner
+	line   int
+	fields int
+	field  Field
+	err    error
+}
+
+//maxLine is the longest reply line a FieldScanner accepts.
+const maxLine = 1<<20
+
+//NewFieldScanner returns a FieldScanner reading from r.
+func NewFieldScanner(r io.Reader) (*FieldScanner){
+	lines := bufio.NewScanner(r)
+	lines.Buffer(nil, maxLine)
+	return &FieldScanner{lines : lines}
+}
+
+//Scan advances to the next field, which is then available from Field.  It
+//returns false at the end of the body or on an error, which Err reports.
+func (s *FieldScanner) Scan() (bool){
+	if(s.err != nil){ return false }
+	for s.lines.Scan(){
+		s.line++
+		field, ok, err := parseLine(s.line, s.lines.Text())
+		if(err != nil){ s.err = err; return false }
+		if(!ok){ continue }
+		s.field = field
+		s.fields++
+		return true
+	}
+	s.err = s.lines.Err()
+	if(errors.Is(s.err, bufio.ErrTooLong)){ s.err = &ParseError{Line : s.line+1, Err : s.err} }
+	if(s.err == nil && s.fields == 0){ s.err = errEmpty() }
+	return false
+}
+
+//Field returns the field read by the last call to Scan.
+func (s *FieldScanner) Field() (Field){ return s.field }
+
+//Err returns the error that stopped Scan, or nil at the end of a good body.
+func (s *FieldScanner) Err() (error){ return s.err }
+
+//validFieldName accepts the names the API uses: letters, digits and underscores,
+//plus the hyphens and dots of extra fields (login-userid) stored by auth_init.
+func validFieldName(name string) (bool){
+	if(name == ""){ return false }
+	for _, c := range name{
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"github.com/secesh/gantlabs"
+	"iter"
+)
+
+//Accounts lists the accounts that arg (an AccountGetAllRequest or
+//AccountQuery, or nil for all) selects, as AccountGetAll does, but one at a
+//time as the reply arrives: each record_N line is decoded and checked against
+//the request's client-side filters before the next is read, so the list is
+//never held in memory.  Breaking out of the loop ends the request.
+//
+//An error ends the sequence: it is yielded with a zero Account, once.  A
+//reply with a resultcode other than 0 is reported as resp.Err() would.
+//
+//Example:
+//  for account, err := range ant.Accounts(innGateApi.AccountQuery{}.Creator("admin").Enabled(true)){
+//    if(err != nil){ panic(err) }
+//    fmt.Println(account.Code, account.ValidUntil)
+//  }
+func (api *Host) Accounts(arg interface{}) (iter.Seq2[Account, error]){
+	request   := accountGetAllRequest(arg)
+	request.op = "account_get_all"
+	
+	return func(yield func(Account, error) (bool)){
+		err := request.Validate()
+		if(err != nil){ yield(Account{}, err); return }
+		
+		stopped := false
+		var failed error
+		fields, err := api.stream(request.op, request.params(api.location), func(record antlabs.Field) (bool){
+			account, err := decodeAccountRecord(record, api.location)
+			if(err != nil){ failed = err; return false }
+			if(!request.Match(account)){ return true }
+			stopped = !yield(account, nil)
+			return !stopped
+		})
+		switch {
+		case stopped:
+			return
+		case failed != nil:
+			yield(Account{}, failed)
+			return
+		case err != nil:
+			yield(Account{}, err)
+			return
+		}
+		
+		var common ResponseCommon
+		err = common.findCommoners(fields)
+		if(err == nil){ err = common.Err() }
+		if(err != nil){ yield(Account{}, err) }
+	}
+}
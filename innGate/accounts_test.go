@@ -0,0 +1,85 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"testing"
+)
+
+//TestAccounts streams the fake's accounts: all of them, the first only, and
+//those a query selects, on the gateway and on the reply.
+func TestAccounts(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	var client innGateApi.Client = gw.Host()
+	
+	admin, err := client.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, Count : 3})
+	if(err != nil){ t.Fatal(err) }
+	if err := admin.Err(); err != nil{ t.Fatal(err) }
+	bob, err := client.AccountAdd(innGateApi.AccountAddRequest{Creator : "bob", Type : innGateApi.AccountTypeCode})
+	if(err != nil){ t.Fatal(err) }
+	if err := bob.Err(); err != nil{ t.Fatal(err) }
+	
+	codes := func(arg interface{}) (codes []string){
+		for account, err := range client.Accounts(arg){
+			if(err != nil){ t.Fatal(err) }
+			codes = append(codes, account.Code)
+		}
+		return codes
+	}
+	if got := codes(nil); len(got) != 4{ t.Errorf("listed %q, want 4 accounts", got) }
+	if got := codes(innGateApi.AccountQuery{}.Creator("bob")); len(got) != 1 || got[0] != bob.Codes[0]{ t.Errorf("listed %q for bob, want %q", got, bob.Codes) }
+	if got := codes(innGateApi.AccountQuery{}.Creator("admin").Enabled(false)); len(got) != 0{ t.Errorf("listed %q disabled, want none", got) }
+	if got := codes(innGateApi.AccountGetAllRequest{Creator : "admin"}); len(got) != 3{ t.Errorf("listed %q for admin, want 3", got) }
+	
+	n := 0
+	for _, err := range client.Accounts(nil){
+		if(err != nil){ t.Fatal(err) }
+		n++
+		break
+	}
+	if(n != 1){ t.Errorf("got %d accounts before break, want 1", n) }
+	if got := codes(nil); len(got) != 4{ t.Errorf("after a break, listed %q, want 4 accounts", got) }
+}
+
+//TestMockAccounts checks that a Mock lists what AccountGetAllFunc answers,
+//with the client-side filters applied, and records the call as
+//account_get_all.
+func TestMockAccounts(t *testing.T){
+	mock := &innGateApi.Mock{}
+	mock.AccountGetAllFunc = func(arg interface{}) (*innGateApi.AccountGetAllResponse, error){
+		common := innGateApi.ResponseCommon{Op : "account_get_all", Result : "ok"}
+		return &innGateApi.AccountGetAllResponse{ResponseCommon : common, Accounts : []innGateApi.Account{{Code : "k2m4p", Enable : true}, {Code : "x7q2z"}}}, nil
+	}
+	var codes []string
+	for account, err := range mock.Accounts(innGateApi.AccountQuery{}.Creator("admin")){
+		if(err != nil){ t.Fatal(err) }
+		codes = append(codes, account.Code)
+	}
+	if(len(codes) != 2){ t.Errorf("listed %q, want 2 accounts", codes) }
+	codes = nil
+	for account, err := range mock.Accounts(innGateApi.AccountQuery{}.Enabled(true)){
+		if(err != nil){ t.Fatal(err) }
+		codes = append(codes, account.Code)
+	}
+	if(len(codes) != 1 || codes[0] != "k2m4p"){ t.Errorf("listed %q enabled, want only k2m4p", codes) }
+	if n := len(mock.CallsTo("account_get_all")); n != 2{ t.Errorf("recorded %d account_get_all calls, want 2", n) }
+}
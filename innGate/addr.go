@@ -0,0 +1,66 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"github.com/secesh/gantlabs"
+)
+
+//ParseMac parses a 48-bit MAC address in any of the usual notations:
+//00:11:25:87:0B:7D, 00-11-25-87-0b-7d, 0011.2587.0b7d or 001125870B7D.
+func ParseMac(s string) (mac net.HardwareAddr, err error){
+	if(len(s) == 12 && strings.Trim(s, "0123456789abcdefABCDEF") == ""){
+		s = s[0:2] + ":" + s[2:4] + ":" + s[4:6] + ":" + s[6:8] + ":" + s[8:10] + ":" + s[10:12]
+	}
+	mac, err = net.ParseMAC(s)
+	if(err != nil){ return nil, err }
+	if(len(mac) != 6){ return nil, errors.New("innGateApi: not a 48-bit MAC address: " + s) }
+	return mac, nil
+}
+
+//MustParseMac is ParseMac for constants; it panics if s is not a MAC address.
+func MustParseMac(s string) (net.HardwareAddr){
+	mac, err := ParseMac(s)
+	if(err != nil){ panic(err) }
+	return mac
+}
+
+//macString writes mac as the gateway does: upper case and colon separated.
+func macString(mac net.HardwareAddr) (string){ return strings.ToUpper(mac.String()) }
+
+//decodeMac decodes a MAC address from the gateway; blank is nil.
+func decodeMac(v antlabs.Field, s string) (mac net.HardwareAddr, err error){
+	if(s == ""){ return nil, nil }
+	mac, err = ParseMac(s)
+	if(err != nil){ return nil, parseErr(v, err) }
+	return mac, nil
+}
+
+//decodeIp decodes an IP address from the gateway; blank is the zero Addr.
+func decodeIp(v antlabs.Field, s string) (ip netip.Addr, err error){
+	if(s == ""){ return netip.Addr{}, nil }
+	ip, err = netip.ParseAddr(s)
+	if(err != nil){ return netip.Addr{}, parseErr(v, err) }
+	return ip, nil
+}
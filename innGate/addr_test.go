@@ -0,0 +1,54 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"testing"
+)
+
+func TestParseMac(t *testing.T){
+	const want = "00:11:25:87:0b:7d"
+	for _, c := range []struct{
+		in string
+		ok bool
+	}{
+		{"00:11:25:87:0B:7D", true},
+		{"00:11:25:87:0b:7d", true},
+		{"00-11-25-87-0B-7D", true},
+		{"0011.2587.0b7d",    true},
+		{"001125870B7D",      true},
+		{"001125870b7D",      true},
+		{"",                        false},
+		{"00:11:25:87:0B",          false},
+		{"00112587",                false},
+		{"001125870B7G",            false},
+		{"00:11:25:87:0B:7D:00:01", false}, //EUI-64
+		{"0011.2587.0b7d.0001",     false},
+		{"not a mac",               false},
+	}{
+		mac, err := innGateApi.ParseMac(c.in)
+		if(!c.ok){
+			if(err == nil){ t.Errorf("ParseMac(%q): got %v, want an error", c.in, mac) }
+			continue
+		}
+		if(err != nil){ t.Errorf("ParseMac(%q): %v", c.in, err); continue }
+		if(mac.String() != want){ t.Errorf("ParseMac(%q): got %v, want %s", c.in, mac, want) }
+	}
+}
@@ -20,58 +20,73 @@ package innGateApi
 
 import (
 	"github.com/secesh/gantlabs"
-	//"fmt"
+	"fmt"
 	"strconv"
-	"regexp"
 	"strings"
 	"errors"
 	"time"
-	"html"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
 )
 
 
-type responseCommon struct{
-	Op            string
-	Result        string
-	Resultcode    int64
-	Error         string
-	ModuleVersion float64
+//ResponseCommon holds the fields the gateway sends with every reply.  It is
+//embedded in each of the XxxResponse types, so they all share these fields and
+//the Err method.
+type ResponseCommon struct{
+	Op            string  //the op the reply answers, e.g. "account_add"
+	Result        string  //"ok" or "error"
+	Resultcode    int64   //0 on success; see the ANTLabs API for the others
+	Error         string  //description of the error, if any
+	ModuleVersion float64 //version of the module that handled the op
 }
 type requestCommon struct{
 	op         string
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////
-//findCommoners scans the field:value map for API elements common to every API response.
-func (common *responseCommon) findCommoners(parsed_body [][]string) (err error){
-	for _, v := range parsed_body{
-		switch v[1]{
+//findCommoners scans the fields for API elements common to every API response.
+//Only a field that cannot be decoded is an error here; whether the gateway
+//reported success is left to the caller (see Err).
+func (common *ResponseCommon) findCommoners(fields []antlabs.Field) (err error){
+	for _, v := range fields{
+		switch v.Name{
 		case "op":
-			common.Op = v[2]
+			common.Op = v.Value
 		case "result":
-			common.Result = v[2]
+			common.Result = v.Value
 		case "resultcode":
-			common.Resultcode, err = strconv.ParseInt(v[2], 10, 64)
-			if(err != nil){ return }
+			common.Resultcode, err = strconv.ParseInt(v.Value, 10, 64)
+			if(err != nil){ return parseErr(v, err) }
 		case "error":
-			common.Error = v[2]
+			common.Error = v.Value
 		case "version":
 			//This will not be valid if the op=api_module.  The API says it
 			//assigns version twice, but in practice it only sends version once.
 			//The version sent by the API refers to the module about which the
 			//inquiry is made (not of api_module itself)
-			common.ModuleVersion, err = strconv.ParseFloat(v[2], 64)
-			if(err != nil){ return }
+			common.ModuleVersion, err = strconv.ParseFloat(v.Value, 64)
+			if(err != nil){ return parseErr(v, err) }
 		}
 	}
-	
-	if(len(common.Error)    >0){err = errors.New("Error: " + common.Error)}
-	if(len(common.Op)      ==0){err = errors.New("Missing expected field in reply (op).")}
-	if(len(common.Result)  ==0){err = errors.New("Missing expected field in reply (result).")}
-	if(common.Resultcode   !=0){err = errors.New("Resultcode is not OK (" + strconv.FormatInt(common.Resultcode, 10) + ").")}
-    if(common.ModuleVersion==0){err = errors.New("Missing expected field in reply (version).")}
-	
-	return
+	return nil
+}
+
+//Err reports why the gateway did not carry out the request, or nil if it did.
+func (common *ResponseCommon) Err() (err error){
+	if(len(common.Op)      ==0){ return errors.New("Missing expected field in reply (op).") }
+	if(len(common.Result)  ==0){ return errors.New("Missing expected field in reply (result).") }
+	if(common.Resultcode ==401 && common.Op == "plan_get_id"){ return fmt.Errorf("%w: %s (401).", ErrUnknownPlan, common.Error) }
+	if(len(common.Error)    >0){ return errors.New("Error: " + common.Error + " (" + strconv.FormatInt(common.Resultcode, 10) + ").") }
+	if(common.Resultcode   !=0){ return errors.New("Resultcode is not OK (" + strconv.FormatInt(common.Resultcode, 10) + ").") }
+	return nil
+}
+
+//parseErr ties a decoding error to the line of the reply it came from.
+func parseErr(field antlabs.Field, err error) (error){
+	return &antlabs.ParseError{Line : field.Line, Field : field.Name, Err : err}
 }
 //////////////////////////////////////////////////////////
 
@@ -82,36 +97,44 @@ func (common *responseCommon) findCommoners(parsed_body [][]string) (err error){
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.Module(innGateApi.ModuleRequest{Module : "api_modules"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nAPI Module:", resp.Version)
-func (api *Host) Module(request ModuleRequest) (result *moduleResponse, err error){
-	ant := api.ant()
+func (api *Host) Module(request ModuleRequest) (result *ModuleResponse, err error){
 	request.op     = "api_module"
-	result         = &moduleResponse{}
+	result         = &ModuleResponse{}
 	
-	parsed_body, err := ant.InnGateApiRequest("api_password="+api.Pass+"&op="+request.op+"&module="+request.Module)
+	err = request.Validate()
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	fields, err := api.request(request.op, url.Values{"module" : {request.Module}})
+	if( err != nil){ return nil, err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//ModuleResponse is the reply to op=api_module.  Version is the version of
+//the module asked about (the same value as ModuleVersion).
+type ModuleResponse struct{
+	ResponseCommon
+	Version float64
+}
+func (result *ModuleResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	for _, v := range fields{
+		switch v.Name{
 		case "version":
-			result.Version, err = strconv.ParseFloat(v[2], 64)
-			if(err != nil){ return nil, err }
+			result.Version, err = strconv.ParseFloat(v.Value, 64)
+			if(err != nil){ return parseErr(v, err) }
 		}
 	}
 	
-	return result, nil
-}
-type moduleResponse struct{
-	responseCommon
-	Version float64
+	return nil
 }
 type ModuleRequest struct{
 	requestCommon
@@ -124,50 +147,65 @@ type ModuleRequest struct{
 //  This module does not require or accept any arguments.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.Modules()
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nAPI_Version:", resp.ApiModules)
-func (api *Host) Modules() (result *modulesResponse, err error){
-	ant := api.ant()
+func (api *Host) Modules() (result *ModulesResponse, err error){
 	request     := modulesRequest{}
 	request.op   = "api_modules" 
 	
-	result = &modulesResponse{}
-	result.Modules = make(map[string]float64) //initalize the map so we can assign values in it later.
+	result = &ModulesResponse{}
 	
-	parsed_body, err := ant.InnGateApiRequest("api_password="+api.Pass+"&op="+request.op)
+	fields, err := api.request(request.op, nil)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//ModulesResponse is the reply to op=api_modules.  Modules maps each module
+//name to its version.
+type ModulesResponse struct{
+	ResponseCommon
+	Count   int64
+	Modules map[string]float64
+}
+func (result *ModulesResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	result.Modules = make(map[string]float64) //initalize the map so we can assign values in it later.
+	for _, v := range fields{
+		switch v.Name{
 		case "count":
-			result.Count, err = strconv.ParseInt(v[2], 10, 64)
-			if(err != nil){ return nil, err }
+			result.Count, err = strconv.ParseInt(v.Value, 10, 64)
+			if(err != nil){ return parseErr(v, err) }
 	 	case "modules":
-	 		modules := strings.Split(v[2], "|")
-	 		for _, v := range modules{
-	 			module := strings.Split(v, " ")
-	 			moduleName         := module[0]
-	 			moduleVersion, err := strconv.ParseFloat(module[1], 64)
-	 			if(err != nil){ return nil, err }
-	 			
-	 			result.Modules[moduleName] = moduleVersion
-	 		}
+	 		result.Modules, err = DecodeModules(v)
+	 		if(err != nil){ return err }
 		}
 	}
 	
-	return result, nil
+	return nil
 }
-type modulesResponse struct{
-	responseCommon
-	Count   int64
-	Modules map[string]float64
+
+//DecodeModules decodes the modules field of an api_modules reply: a pipe
+//separated list of module names, each followed by a space and its version.
+func DecodeModules(field antlabs.Field) (modules map[string]float64, err error){
+	modules = make(map[string]float64)
+	if(field.Value == ""){ return modules, nil }
+	for _, v := range strings.Split(field.Value, "|"){
+		module := strings.Fields(v)
+		if(len(module) != 2){ return nil, parseErr(field, errors.New("expected \"name version\", got " + strconv.Quote(v))) }
+		moduleName         := module[0]
+		moduleVersion, err := strconv.ParseFloat(module[1], 64)
+		if(err != nil){ return nil, parseErr(field, err) }
+		
+		modules[moduleName] = moduleVersion
+	}
+	return modules, nil
 }
 type modulesRequest struct{
 	requestCommon
@@ -181,47 +219,56 @@ type modulesRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AuthAuthenticate(innGateApi.AuthAuthenticateRequest{Code: "abc123"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nResult of authentication request:", resp.Result)
-func (api *Host) AuthAuthenticate(request AuthAuthenticateRequest) (result *authAuthenticateResponse, err error){
-	ant := api.ant()
+func (api *Host) AuthAuthenticate(request AuthAuthenticateRequest) (result *AuthAuthenticateResponse, err error){
 	request.op   = "auth_authenticate" 
 	
-	result = &authAuthenticateResponse{}
+	result = &AuthAuthenticateResponse{}
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.Code != ""){ query += "&code=" + request.Code }
-	if(request.UserId != ""){ query += "&userid=" + html.EscapeString(request.UserId)}
-	if(request.Password != ""){ query += "&password=" + html.EscapeString(request.Password)}
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	params := url.Values{}
+	if(request.Code != ""){ params.Set("code", request.Code) }
+	if(request.UserId != ""){ params.Set("userid", request.UserId)}
+	if(request.Password != ""){ params.Set("password", request.Password)}
+	if(request.Mode != AuthModeDefault){ params.Set("mode", request.Mode.String()) }
+	
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AuthAuthenticateResponse is the reply to op=auth_authenticate.  RadiusAttrs
+//holds the "Name=Value" RADIUS attributes, when mode=radius.
+type AuthAuthenticateResponse struct{
+	ResponseCommon
+	RadiusAttrs []string
+}
+func (result *AuthAuthenticateResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 		case "radiusattrs":
-			result.RadiusAttrs = strings.Split(v[2], "|")
+			result.RadiusAttrs = strings.Split(v.Value, "|")
 		}
 	}
 	
-	return result, nil
-}
-type authAuthenticateResponse struct{
-	responseCommon
-	RadiusAttrs []string
+	return nil
 }
 type AuthAuthenticateRequest struct{
 	requestCommon
 	Code string
 	UserId, Password string
-	Mode string
+	Mode AuthMode
 }
 //////////////////////////////////////////////////////////
 
@@ -232,83 +279,95 @@ type AuthAuthenticateRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AuthLogin(innGateApi.AuthLoginRequest{Sid : "86cb1a5deb036467a9c2bc36e13971ef"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nLogin result:", resp.Result)
-func (api *Host) AuthLogin(request AuthLoginRequest) (result *authLoginResponse, err error){
-	ant := api.ant()
+func (api *Host) AuthLogin(request AuthLoginRequest) (result *AuthLoginResponse, err error){
 	request.op = "auth_login" 
-	result     = &authLoginResponse{}
+	result     = &AuthLoginResponse{}
+	
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	query := "api_password="+api.Pass+"&op="+request.op
+	params := url.Values{}
 	if(request.Sid != ""){ 
-		query += "&sid=" + html.EscapeString(request.Sid) 
+		params.Set("sid", request.Sid) 
 	}else{
 		//If we're not using SID, we must be using the following.  We don't need to check for
 		//values because if we're missing parameters the API will cause the request to fail.
-		query += "&client_mac=" + html.EscapeString(request.ClientMac)
-		query += "&client_ip=" + html.EscapeString(request.ClientIp)
-		query += "&location_index=" + strconv.FormatInt(request.LocationIndex, 10)
-		query += "&ppli=" + html.EscapeString(request.Ppli)
+		params.Set("client_mac", macString(request.ClientMac))
+		params.Set("client_ip", request.ClientIp.String())
+		params.Set("location_index", strconv.FormatInt(request.LocationIndex, 10))
+		params.Set("ppli", request.Ppli)
 	}
-	if(request.Mode != ""){ query += "&mode=" + html.EscapeString(request.Mode) }
-	if(request.Code != ""){ query += "&code=" + html.EscapeString(request.Code) }
-	if(request.UserId != ""){ query += "&userid=" + html.EscapeString(request.UserId) }
-	if(request.Password != ""){ query += "&password=" + html.EscapeString(request.Password) }
-	if(request.Secret != ""){ query += "&secret=" + html.EscapeString(request.Secret) }
+	if(request.Mode != LoginModeDefault){ params.Set("mode", request.Mode.String()) }
+	if(request.Code != ""){ params.Set("code", request.Code) }
+	if(request.UserId != ""){ params.Set("userid", request.UserId) }
+	if(request.Password != ""){ params.Set("password", request.Password) }
+	if(request.Secret != ""){ params.Set("secret", request.Secret) }
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AuthLoginResponse is the reply to op=auth_login.
+type AuthLoginResponse struct{
+	ResponseCommon
+	RequestedUrl string
+	PreLoginUrl  string
+	PublicIp     netip.Addr
+	Sid          string
+	ClientMac    net.HardwareAddr
+	ClientIp     netip.Addr
+	Ppli         string
+	Vlan         string
+}
+func (result *AuthLoginResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 	 	case "requestedURL":
-			result.RequestedUrl = v[2]
+			result.RequestedUrl = v.Value
 		case "preloginURL":
-			result.PreLoginUrl = v[2]
+			result.PreLoginUrl = v.Value
 		case "publicip":
-			result.PublicIp = v[2]
+			result.PublicIp, err = decodeIp(v, v.Value)
+			if(err != nil){ return err }
 		case "sid":
-			result.Sid = v[2]
+			result.Sid = v.Value
 		case "client_mac":
-			result.ClientMac = v[2]
+			result.ClientMac, err = decodeMac(v, v.Value)
+			if(err != nil){ return err }
 		case "client_ip":
-			result.ClientIp = v[2]
+			result.ClientIp, err = decodeIp(v, v.Value)
+			if(err != nil){ return err }
 		case "ppli":
-			result.Ppli = v[2]
+			result.Ppli = v.Value
 		case "vlan":
-			result.Vlan = v[2]
+			result.Vlan = v.Value
 		}
 	}
 	
-	return result, nil
-}
-type authLoginResponse struct{
-	responseCommon
-	RequestedUrl string
-	PreLoginUrl  string
-	PublicIp     string
-	Sid          string
-	ClientMac    string
-	ClientIp     string
-	Ppli         string
-	Vlan         string
+	return nil
 }
 type AuthLoginRequest struct{
 	requestCommon
 	//Required:
 	Sid string
 	//or:
-	ClientMac, ClientIp, Ppli string
+	ClientMac     net.HardwareAddr
+	ClientIp      netip.Addr
+	Ppli          string
 	LocationIndex int64
 	//Optional:
-	Mode string
+	Mode LoginMode
 	Code string
 	UserId, Password string
 	Secret string
@@ -322,52 +381,60 @@ type AuthLoginRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AuthLogout(innGateApi.AuthLogoutRequest{Sid : "86cb1a5deb036467a9c2bc36e13971ef"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nLogout result:", resp.Result)
-func (api *Host) AuthLogout(request AuthLogoutRequest) (result *authLogoutResponse, err error){
-	ant := api.ant()
+func (api *Host) AuthLogout(request AuthLogoutRequest) (result *AuthLogoutResponse, err error){
 	request.op = "auth_logout" 
-	result     = &authLogoutResponse{}
+	result     = &AuthLogoutResponse{}
+	
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.Sid != ""){ query += "&sid=" + html.EscapeString(request.Sid) }
-	if(request.ClientMac != ""){ query += "&client_mac=" + html.EscapeString(request.ClientMac) }
+	params := url.Values{}
+	if(request.Sid != ""){ params.Set("sid", request.Sid) }
+	if(request.ClientMac != nil){ params.Set("client_mac", macString(request.ClientMac)) }
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AuthLogoutResponse is the reply to op=auth_logout.
+type AuthLogoutResponse struct{
+	ResponseCommon
+	Accounting   string
+	Sid          string
+	ClientMac    net.HardwareAddr
+}
+func (result *AuthLogoutResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 		case "accounting":
-			result.Accounting = v[2]
+			result.Accounting = v.Value
 		case "sid":
-			result.Sid = v[2]
+			result.Sid = v.Value
 		case "client_mac":
-			result.ClientMac = v[2]
+			result.ClientMac, err = decodeMac(v, v.Value)
+			if(err != nil){ return err }
 		}
 	}
 	
-	return result, nil
-}
-type authLogoutResponse struct{
-	responseCommon
-	Accounting   string
-	Sid          string
-	ClientMac    string
+	return nil
 }
 type AuthLogoutRequest struct{
 	requestCommon
 	//Required:
 	Sid string
 	//or:
-	ClientMac string
+	ClientMac net.HardwareAddr
 }
 //////////////////////////////////////////////////////////
 
@@ -378,71 +445,83 @@ type AuthLogoutRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AuthInit(innGateApi.AuthInitRequest{
-//     ClientMac     : "00:00:00:00:00:00",  //TODO: unknown formatting
-//     ClientIP      : "10.1.1.42",          //TODO: confirm formatting
-//     LocationIndex : 0,
-//     Ppli          : ""                    //TODO: I forget what this is.
+//     ClientMac     : innGateApi.MustParseMac("00:11:25:87:0b:7d"), //any notation; sent as 00:11:25:87:0B:7D
+//     ClientIp      : netip.MustParseAddr("10.1.1.42"),
+//     LocationIndex : "0",
+//     Ppli          : "eth0.210",                                  //the port-location the client is on
 //  })
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nInit result:", resp.Result)
-func (api *Host) AuthInit(request AuthInitRequest) (result *authInitResponse, err error){
-	ant := api.ant()
+func (api *Host) AuthInit(request AuthInitRequest) (result *AuthInitResponse, err error){
 	request.op = "auth_init" 
-	result     = &authInitResponse{}
+	result     = &AuthInitResponse{}
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.ClientMac != ""){ query += "&client_mac=" + request.ClientMac }
-	if(request.ClientIp  != ""){ query += "&client_ip="  + request.ClientIp  }
-	if(request.LocationIndex != ""){ query += "&location_index=" + request.LocationIndex }
-	if(request.Ppli != ""){ query += "&ppli=" + request.Ppli }
-	if(request.NewSid != 0){ query += "&new_sid=" + strconv.FormatInt(request.NewSid, 10) }
-	if(request.Extra  != ""){ query += request.Extra }
+	err = request.Validate()
+	if( err != nil){ return nil, err }
+	
+	params := url.Values{}
+	if(request.ClientMac != nil){ params.Set("client_mac", macString(request.ClientMac)) }
+	if(request.ClientIp.IsValid()){ params.Set("client_ip", request.ClientIp.String()) }
+	if(request.LocationIndex != ""){ params.Set("location_index", request.LocationIndex) }
+	if(request.Ppli != ""){ params.Set("ppli", request.Ppli) }
+	if(request.NewSid != 0){ params.Set("new_sid", strconv.FormatInt(request.NewSid, 10)) }
+	if(request.Extra  != ""){
+		extra, _ := url.ParseQuery(strings.TrimPrefix(request.Extra, "&")) //checked by Validate
+		for k, v := range extra{ params[k] = v }
+	}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AuthInitResponse is the reply to op=auth_init.
+type AuthInitResponse struct{
+	ResponseCommon
+	Sid       string
+	ClientMac net.HardwareAddr
+	ClientIp  netip.Addr
+	Ppli      string
+	Vlan      string
+}
+func (result *AuthInitResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 		case "sid":
-			result.Sid       = v[2]
+			result.Sid       = v.Value
 		case "client_mac":
-			result.ClientMac = v[2]
+			result.ClientMac, err = decodeMac(v, v.Value)
+			if(err != nil){ return err }
 		case "client_ip":
-			result.ClientIp  = v[2]
+			result.ClientIp, err = decodeIp(v, v.Value)
+			if(err != nil){ return err }
 		case "ppli":
-			result.Ppli      = v[2]
+			result.Ppli      = v.Value
 		case "vlan":
-			result.Vlan      =v[2]
+			result.Vlan      =v.Value
 		}
 	}
 	
-	return result, nil
-}
-type authInitResponse struct{
-	responseCommon
-	Sid       string
-	ClientMac string
-	ClientIp  string
-	Ppli      string
-	Vlan      string
+	return nil
 }
 type AuthInitRequest struct{
 	requestCommon
 	//Required:
-	ClientMac     string
-	ClientIp      string
+	ClientMac     net.HardwareAddr
+	ClientIp      netip.Addr
 	LocationIndex string
 	Ppli          string
 	//Optional:
 	NewSid int64
-	Extra  string //up to you to make it a proper query string!  Start with an &!
+	Extra  string //extra parameters as a query string, e.g. "room=101&name=Smith"
 }
 //////////////////////////////////////////////////////////
 
@@ -453,40 +532,48 @@ type AuthInitRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
-//  resp, err := ant.AuthUpdate(innGateApi.AuthUpdateRequest{ClientMac : "00:00:00:00:00:00"})
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
+//  resp, err := ant.AuthUpdate(innGateApi.AuthUpdateRequest{ClientMac : innGateApi.MustParseMac("00:11:25:87:0B:7D"), Volume : innGateApi.Set(innGateApi.ByteSize(0))})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nUpdate result:", resp.Result)
-func (api *Host) AuthUpdate(request AuthUpdateRequest) (result *authUpdateResponse, err error){
-	ant := api.ant()
+func (api *Host) AuthUpdate(request AuthUpdateRequest) (result *AuthUpdateResponse, err error){
 	request.op = "auth_update"
-	result     = &authUpdateResponse{}
-	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.ClientMac != ""){ query += "&client_mac=" + html.EscapeString(request.ClientMac) }
-	if(request.Duration != ""){ query += "&duration=" + request.Duration }
-	if(request.Volume != ""){ query += "&volume=" + request.Volume }
+	result     = &AuthUpdateResponse{}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	err = request.Validate()
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	params := url.Values{}
+	if(request.ClientMac != nil){ params.Set("client_mac", macString(request.ClientMac)) }
+	if d, ok := request.Duration.Get(); ok{ params.Set("duration", formatMinutes(d)) }
+	if b, ok := request.Volume.Get(); ok{ params.Set("volume", formatBytes(b)) }
 	
+	fields, err := api.request(request.op, params)
+	if( err != nil){ return nil, err }
+	
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
 	return result, nil
 }
-type authUpdateResponse struct{
-	responseCommon
+//AuthUpdateResponse is the reply to op=auth_update, which carries only the
+//common fields.
+type AuthUpdateResponse struct{
+	ResponseCommon
+}
+func (result *AuthUpdateResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	return nil
 }
 type AuthUpdateRequest struct{
 	requestCommon
 	//Required:
-	ClientMac string
-	//Optional:
-	Duration  string //minutes.(int) expressed as string
-	Volume    string //bytes.(int) expressed as string
+	ClientMac net.HardwareAddr
+	//Optional, but one is required:
+	Duration  Optional[time.Duration] //whole minutes or UnlimitedDuration; replaces the time left
+	Volume    Optional[ByteSize]      //added to the volume left, or UnlimitedVolume
 }
 
 //  SidGet performs the an API request for op=sid_get
@@ -496,42 +583,63 @@ type AuthUpdateRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.SidGet(innGateApi.SidGetRequest{sid : "86cb1a5deb036467a9c2bc36e13971ef"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("Client MAC:", resp.ClientMac)
-func (api *Host) SidGet(request SidGetRequest) (result *sidGetResponse, err error){
-	ant := api.ant()
+func (api *Host) SidGet(request SidGetRequest) (result *SidGetResponse, err error){
 	request.op = "sid_get"
-	result     = &sidGetResponse{}
+	result     = &SidGetResponse{}
+	
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	query += "&sid=" + request.Sid
+	params := url.Values{}
+	params.Set("sid", request.Sid)
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//SidGetResponse is the reply to op=sid_get.  Extra holds any field the
+//gateway sent that has no field of its own (e.g. parameters given to
+//auth_init).
+type SidGetResponse struct{
+	ResponseCommon
+	Sid           string
+	ClientMac     net.HardwareAddr
+	Ppli          string
+	Vlan          string
+	ClientIp      netip.Addr
+	LocationIndex string
+	Extra         map[string]string
+}
+func (result *SidGetResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
 	//initialize the ExtraFields map so we can add to it later if necessary:
 	result.Extra = make(map[string]string)
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 	 	case "sid":
-	 		result.Sid = v[2]
+	 		result.Sid = v.Value
 	 	case "client_mac":
-	 		result.ClientMac = v[2]
+	 		result.ClientMac, err = decodeMac(v, v.Value)
+	 		if(err != nil){ return err }
 	 	case "ppli":
-	 		result.Ppli = v[2]
+	 		result.Ppli = v.Value
  		case "vlan":
- 			result.Vlan = v[2]
+ 			result.Vlan = v.Value
  		case "client_ip":
- 			result.ClientIp = v[2]
+ 			result.ClientIp, err = decodeIp(v, v.Value)
+ 			if(err != nil){ return err }
  		case "location_index":
- 			result.LocationIndex = v[2]
+ 			result.LocationIndex = v.Value
  		//Ignore the commoners.
  		case "op":
  		case "version":
@@ -540,21 +648,11 @@ func (api *Host) SidGet(request SidGetRequest) (result *sidGetResponse, err erro
  		case "error":
  		//Handle what's left as an extra-field
  		default:
- 			result.Extra[v[1]] = v[2]
+ 			result.Extra[v.Name] = v.Value
  		}
  	}
 	
-	return result, nil
-}
-type sidGetResponse struct{
-	responseCommon
-	Sid           string
-	ClientMac     string
-	Ppli          string
-	Vlan          string
-	ClientIp      string
-	LocationIndex string
-	Extra         map[string]string
+	return nil
 }
 type SidGetRequest struct{
 	requestCommon
@@ -570,87 +668,102 @@ type SidGetRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AccountAdd(innGateApi.AccountAddRequest{})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nLogin result:", resp.Result)
-func (api *Host) AccountAdd(request AccountAddRequest) (result *accountAddResponse, err error){
-	ant := api.ant()
+func (api *Host) AccountAdd(request AccountAddRequest) (result *AccountAddResponse, err error){
 	request.op = "account_add"
-	result     = &accountAddResponse{}
-	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.Type != ""){ query += "&type=" + request.Type }
-	if(request.UserId != ""){ query += "&userid=" + request.UserId }
-	if(request.UserIdFormat != ""){ query += "&userid_format=" + request.UserIdFormat }
-	if(request.UserIdLength != 0){ query += "&userid_length=" + strconv.FormatInt(request.UserIdLength, 10) }
-	if(request.UserIdPrefix != ""){ query += "&userid_prefix=" + request.UserIdPrefix }
-	if(request.UserIdSuffix != ""){ query += "&userid_suffix=" + request.UserIdSuffix }
-	if(request.UserIdStart != ""){ query += "&userid_start=" + request.UserIdStart }
-	if(request.Password != ""){ query += "&password=" + request.Password}
-	if(request.PasswordLength != 0){ query += "&password_length=" + strconv.FormatInt(request.PasswordLength, 10) }
-	if(request.PasswordFormat != ""){ query += "&password_format=" + request.PasswordFormat }
-	if(request.Code != ""){ query += "&code=" + request.Code }
-	if(request.CodeFormat != ""){ query += "&code_format=" + request.CodeFormat}
-	if(request.CodeStart != ""){ query += "&code_start=" + request.CodeStart }
-	if(request.CodeLength != 0){ query += "&code_length=" + strconv.FormatInt(request.CodeLength, 10) }
-	if(request.CodePrefix != ""){ query += "&code_prefix=" + request.CodePrefix}
-	if(request.CodeSuffix != ""){ query += "&code_suffix=" + request.CodeSuffix}
-	if(request.Count >1){ query += "&count=" + strconv.FormatInt(request.Count, 10) }
-	if(request.Description != ""){ query += "&description=" + request.Description }
-	if(request.ValidFrom != time.Time{}){query += "&valid_from=" + strconv.FormatInt(request.ValidFrom.Unix(), 10) }
-	if(request.ValidUntil != time.Time{}){query += "&valid_until=" + strconv.FormatInt(request.ValidUntil.Unix(), 10) }
-	if(request.LoginMax != ""){ query += "&login_max=" + request.LoginMax }
-	if(request.SharingMax != 0){ query += "&sharing_max=" + strconv.FormatInt(request.SharingMax, 10) }
-	if(request.BillingId != ""){ query += "&billing_id=" + request.BillingId }
-	query += "&allowed_login_zone=" + strconv.FormatInt(request.AllowedLoginZone, 10)
-	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	result     = &AccountAddResponse{}
+	
+	err = request.Validate()
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	params := url.Values{}
+	if(request.Creator != ""){ params.Set("creator", request.Creator) }
+	if(request.Type != AccountTypeDefault){ params.Set("type", request.Type.String()) }
+	if(request.UserId != ""){ params.Set("userid", request.UserId) }
+	if(request.UserIdFormat != FormatDefault){ params.Set("userid_format", request.UserIdFormat.String()) }
+	if(request.UserIdLength != 0){ params.Set("userid_length", strconv.FormatInt(request.UserIdLength, 10)) }
+	if(request.UserIdPrefix != ""){ params.Set("userid_prefix", request.UserIdPrefix) }
+	if(request.UserIdSuffix != ""){ params.Set("userid_suffix", request.UserIdSuffix) }
+	if(request.UserIdStart != ""){ params.Set("userid_start", request.UserIdStart) }
+	if(request.Password != ""){ params.Set("password", request.Password)}
+	if(request.PasswordLength != 0){ params.Set("password_length", strconv.FormatInt(request.PasswordLength, 10)) }
+	if(request.PasswordFormat != FormatDefault){ params.Set("password_format", request.PasswordFormat.String()) }
+	if(request.Code != ""){ params.Set("code", request.Code) }
+	if(request.CodeFormat != FormatDefault){ params.Set("code_format", request.CodeFormat.String())}
+	if(request.CodeStart != ""){ params.Set("code_start", request.CodeStart) }
+	if(request.CodeLength != 0){ params.Set("code_length", strconv.FormatInt(request.CodeLength, 10)) }
+	if(request.CodePrefix != ""){ params.Set("code_prefix", request.CodePrefix)}
+	if(request.CodeSuffix != ""){ params.Set("code_suffix", request.CodeSuffix)}
+	if(request.Count >1){ params.Set("count", strconv.FormatInt(request.Count, 10)) }
+	if(request.Description != ""){ params.Set("description", request.Description) }
+	if t, ok := request.ValidFrom.Get(); ok && !t.IsZero(){ params.Set("valid_from", formatTime(t)) }
+	if t, ok := request.ValidUntil.Get(); ok && !t.IsZero(){ params.Set("valid_until", formatTime(t)) } //NoTime() is the default: never
+	if(request.LoginMax.IsSet()){ params.Set("login_max", request.LoginMax.String()) }
+	if(request.SharingMax != 0){ params.Set("sharing_max", strconv.FormatInt(request.SharingMax, 10)) }
+	if(request.BillingId != ""){ params.Set("billing_id", request.BillingId) }
+	if n, ok := request.AllowedLoginZone.Get(); ok{ params.Set("allowed_login_zone", strconv.FormatInt(n, 10)) }
+	
+	planId, ok, err := api.resolvePlan(request.PlanId, request.PlanName)
+	if( err != nil){ return nil, err }
+	if(ok){ params.Set("plan_id", strconv.FormatInt(planId, 10)) }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
-	 	case "created":
-	 		result.Created, err = strconv.ParseInt(v[2], 10, 64)
-	 		if( err != nil){ return nil, err }
-	 	case "userids":
-	 		result.UserIds = strings.Split(v[2], "|")
-	 	case "passwords":
-	 		result.Passwords = strings.Split(v[2], "|")
-	 	case "codes":
-	 		result.Codes = strings.Split(v[2], "|")
-		}
-	}
+	fields, err := api.request(request.op, params)
+	if( err != nil){ return nil, err }
 	
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
 	return result, nil
 }
-type accountAddResponse struct{
-	responseCommon
+//AccountAddResponse is the reply to op=account_add.  UserIds, Passwords and
+//Codes hold one entry per created account, in the same order.
+type AccountAddResponse struct{
+	ResponseCommon
 	Created   int64
 	UserIds   []string
 	Passwords []string
 	Codes     []string
 }
+func (result *AccountAddResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	for _, v := range fields{
+		switch v.Name{
+	 	case "created":
+	 		result.Created, err = strconv.ParseInt(v.Value, 10, 64)
+	 		if( err != nil){ return parseErr(v, err) }
+	 	case "userids":
+	 		result.UserIds = strings.Split(v.Value, "|")
+	 	case "passwords":
+	 		result.Passwords = strings.Split(v.Value, "|")
+	 	case "codes":
+	 		result.Codes = strings.Split(v.Value, "|")
+		}
+	}
+	
+	return nil
+}
 type AccountAddRequest struct{
 	requestCommon
 	//Required:
 	Creator string
 	
-	PlanId string
+	//Resolved to the plan's ID and checked before the account is created (see
+	//PlanByName); a plan that does not exist is ErrUnknownPlan.
+	PlanId int64
 	//or:
 	PlanName string
 	
 	//All the following are optional:
-	Type string
+	Type AccountType
 	
 	UserId string
 	//or:
-	UserIdFormat string //if !UserId ('alpha|alnum|num' default:alpha)
+	UserIdFormat Format //if !UserId (default:alpha)
 	UserIdLength int64  //if !UserId (default:5 minimum:3)
 	UserIdPrefix string //if !UserId (default:'' max_length:20)
 	UserIdSuffix string //if !UserId (default:'' max_length:20)
@@ -660,24 +773,24 @@ type AccountAddRequest struct{
 	Password       string
 	//or
 	PasswordLength int64  //if !Password (default:5 minumum:3)
-	PasswordFormat string //if !Password ('alpha|alnum|num' default:alnum)
+	PasswordFormat Format //if !Password (default:alnum)
 	
 	Code string //between 3 and 10 characters /[a-z0-9]/
 	//or:
-	CodeFormat   string //if !Code ('alpha|'alnum'|'num' defaule:alnum)
-	  CodeLength int64  //if !Code (default:5 minimum:3)
-	  CodePrefix string //if !Code (default:'' min_length:4)
-	  CodeSuffix string //if !Code (default:'' min_length:4)
+	CodeFormat   Format //if !Code (default:alnum)
+	  CodeLength int64  //if !Code (default:5 minimum:3 maximum:10)
+	  CodePrefix string //if !Code (default:'' chars:a-z0-9)
+	  CodeSuffix string //if !Code (default:'' chars:a-z0-9; prefix+length+suffix max:10)
 	CodeStart    string //if !Code a number (expressed as a string) or 'auto'
 	
 	Count        int64     //(default:1 max:100)
 	Description  string    //(max_length:255)
-	ValidFrom    time.Time //time.Time.Unix() will suffice for 'now'  ?(is that ow you get 'now')
-	ValidUntil   time.Time //or nil (or not set)
-	LoginMax     string //(default:'unlimited' otherwise an int expressed as string)
+	ValidFrom    Optional[time.Time] //(default:now)
+	ValidUntil   Optional[time.Time] //unset or NoTime(): never expires
+	LoginMax     LoginMax  //(default:UnlimitedLogins)
 	SharingMax        int64  //default:1 
 	BillingId         string //max_length:100; default:''
-	AllowedLoginZone  int64  //default:0
+	AllowedLoginZone  Optional[int64] //default:0
 }
 //////////////////////////////////////////////////////////
 
@@ -688,136 +801,134 @@ type AccountAddRequest struct{
 //  The example below demonstrates how to send a request.
 //  
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AccountGet(innGateApi.AccountGetRequest{Code : "abc123"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nAccount:", resp)
-func (api *Host) AccountGet(request AccountGetRequest) (result *accountGetResponse, err error){
-	ant := api.ant()
+func (api *Host) AccountGet(request AccountGetRequest) (result *AccountGetResponse, err error){
 	request.op   = "account_get"
-	result       = &accountGetResponse{}
+	result       = &AccountGetResponse{loc : api.location}
+	
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.Code != ""){ query += "&code=" + html.EscapeString(request.Code)}
-	if(request.UserId != ""){ query += "&userid=" + html.EscapeString(request.UserId)}
-	if(request.ClientMac != ""){ query += "&client_mac=" + html.EscapeString(request.ClientMac)}
+	params := url.Values{}
+	if(request.Code != ""){ params.Set("code", request.Code)}
+	if(request.UserId != ""){ params.Set("userid", request.UserId)}
+	if(request.ClientMac != nil){ params.Set("client_mac", macString(request.ClientMac))}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
-	
-	for _, v := range parsed_body{
-		switch v[1]{
-		case "userid":
-			result.UserId = strings.Split(v[2], "|")
-		case "code": 
-			result.Code = strings.Split(v[2], "|")
-		case "sharing_index":
-			for _,v := range strings.Split(v[2], "|"){
-				sharingIndex, err := strconv.ParseInt(v, 10, 64)
-				if(err != nil){ return nil, err }
-				result.SharingIndex = append(result.SharingIndex, sharingIndex)
-			}
-		case "client_mac":
-			result.Code = strings.Split(v[2], "|")
-		case "description":
-			result.Description = strings.Split(v[2], "|")
-		case "enabled":
-			for _,v := range strings.Split(v[2], "|"){
-				if(v == "yes"){
-					result.Enabled = append(result.Enabled, true)
-				}else{
-					result.Enabled = append(result.Enabled, true)
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AccountGetResponse is the reply to op=account_get.  The gateway sends one
+//entry per device sharing an account; they are gathered into one Account
+//each, with the devices in Sharing.  Type, Creator, Accounting and BillingId
+//are not part of the reply and are left blank.
+type AccountGetResponse struct{
+	ResponseCommon
+	Accounts []Account
+	loc      *time.Location //see WithLocation
+}
+func (result *AccountGetResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	//rows holds one account per pipe separated entry, each with one device.
+	var rows []Account
+	for _, v := range fields{
+		if(!accountGetField(v.Name)){ continue }
+		for i, s := range strings.Split(v.Value, "|"){
+			for len(rows) <= i{ rows = append(rows, Account{Sharing : []Sharing{{}}}) }
+			row := &rows[i]
+			switch v.Name{
+			case "userid":
+				row.UserId = s
+			case "code": 
+				row.Code = s
+			case "sharing_index":
+				row.Sharing[0].Index, err = strconv.ParseInt(s, 10, 64)
+				if(err != nil){ return parseErr(v, err) }
+			case "client_mac":
+				row.Sharing[0].ClientMac, err = decodeMac(v, s)
+				if(err != nil){ return err }
+			case "description":
+				row.Description = s
+			case "enabled":
+				row.Enable = (s == "yes")
+			case "valid_from":
+				if(s != ""){ //blank when the account has no valid_from
+					row.ValidFrom, err = ParseTime(s, result.loc)
+					if(err != nil){ return parseErr(v, err) }
 				}
-			}
-		case "valid_from":
-			for _,v := range strings.Split(v[2], "|"){
-				validFrom, err := time.Parse(time.RFC1123Z, v)
-				if(err != nil){ return nil, err }
-				result.ValidFrom = append(result.ValidFrom, validFrom)
-			}
-		case "valid_until":
-			for _,v := range strings.Split(v[2], "|"){
-				validUntil, err := time.Parse(time.RFC1123Z, v)
-				if(err != nil){ return nil, err }
-				result.ValidUntil = append(result.ValidUntil, validUntil)
-			}
-		case "login_limit":
-			for _,v := range strings.Split(v[2], "|"){
-				if(v == "on"){
-					result.LoginLimit = append(result.LoginLimit, true)
-				}else{
-					result.LoginLimit = append(result.LoginLimit, false)
+			case "valid_until":
+				if(s != ""){ //blank when the account has no valid_until
+					row.ValidUntil, err = ParseTime(s, result.loc)
+					if(err != nil){ return parseErr(v, err) }
 				}
-			}
-		case "login_max":
-			for _,v := range strings.Split(v[2], "|"){
-				loginMax, err := strconv.ParseInt(v, 10, 64)
-				if(err != nil){ return nil, err }
-				result.LoginMax = append(result.LoginMax, loginMax)
-			}
-		case "login_count":
-			for _,v := range strings.Split(v[2], "|"){
-				loginCount, err := strconv.ParseInt(v, 10, 64)
-				if(err != nil){ return nil, err }
-				result.LoginCount = append(result.LoginCount, loginCount)
-			}
-		case "sharing_max":
-			for _,v := range strings.Split(v[2], "|"){
-				sharingMax, err := strconv.ParseInt(v, 10, 64)
-				if(err != nil){ return nil, err }
-				result.SharingMax = append(result.SharingMax, sharingMax)
-			}
-		case "plan":
-			result.Plan = strings.Split(v[2], "|")
-		case "duration_balance":
-			result.DurationBalance = strings.Split(v[2], "|")
-		case "volume_balance":
-			result.VolumeBalance = strings.Split(v[2], "|")
-		case "create_time":
-			for _,v := range strings.Split(v[2], "|"){
-				createTime, err := time.Parse(time.RFC1123Z, v)
-				if(err != nil){ return nil, err }
-				result.CreateTime = append(result.CreateTime, createTime)
-			}
-		case "update_time":
-			for _,v := range strings.Split(v[2], "|"){
-				updateTime, err := time.Parse(time.RFC1123Z, v)
-				if(err != nil){ return nil, err }
-				result.UpdateTime = append(result.UpdateTime, updateTime)
+			case "login_limit":
+				row.LoginLimit = (s == "on")
+			case "login_max":
+				row.LoginMax, err = strconv.ParseInt(s, 10, 64)
+				if(err != nil){ return parseErr(v, err) }
+			case "login_count":
+				row.LoginCount, err = strconv.ParseInt(s, 10, 64)
+				if(err != nil){ return parseErr(v, err) }
+			case "sharing_max":
+				row.SharingMax, err = strconv.ParseInt(s, 10, 64)
+				if(err != nil){ return parseErr(v, err) }
+			case "plan":
+				row.UserGroupName = s
+			case "duration_balance":
+				if(s != ""){
+					row.DurationBalance, err = parseDuration(s)
+					if(err != nil){ return parseErr(v, err) }
+				}
+			case "volume_balance":
+				if(s != ""){
+					row.VolumeBalance, err = parseVolume(s, Byte)
+					if(err != nil){ return parseErr(v, err) }
+				}
+			case "create_time":
+				row.CreateTime, err = ParseTime(s, result.loc)
+				if(err != nil){ return parseErr(v, err) }
+			case "update_time":
+				row.UpdateTime, err = ParseTime(s, result.loc)
+				if(err != nil){ return parseErr(v, err) }
 			}
 		}
 	}
 	
-	return result, nil
+	for _, row := range rows{
+		n := len(result.Accounts)
+		if(n > 0 && result.Accounts[n-1].UserId == row.UserId && result.Accounts[n-1].Code == row.Code){
+			result.Accounts[n-1].Sharing = append(result.Accounts[n-1].Sharing, row.Sharing[0])
+			continue
+		}
+		result.Accounts = append(result.Accounts, row)
+	}
+	return nil
 }
-type accountGetResponse struct{
-	responseCommon
-	UserId       []string
-	Code         []string
-	SharingIndex []int64
-	ClientMac    []string
-	Description  []string
-	Enabled      []bool
-	ValidFrom    []time.Time
-	ValidUntil   []time.Time
-	LoginLimit   []bool
-	LoginMax     []int64
-	LoginCount   []int64
-	SharingMax   []int64
-	Plan         []string
-	DurationBalance []string
-	VolumeBalance   []string
-	CreateTime      []time.Time
-	UpdateTime      []time.Time
+
+//accountGetField reports whether name is one of the per-device fields of an
+//account_get reply.
+func accountGetField(name string) (bool){
+	switch name{
+	case "userid", "code", "sharing_index", "client_mac", "description", "enabled", "valid_from", "valid_until",
+		"login_limit", "login_max", "login_count", "sharing_max", "plan", "duration_balance", "volume_balance",
+		"create_time", "update_time":
+		return true
+	}
+	return false
 }
 type AccountGetRequest struct{
 	requestCommon
-	UserId, Code, ClientMac string
+	UserId, Code string
+	ClientMac    net.HardwareAddr
 }
 //////////////////////////////////////////////////////////
 
@@ -828,9 +939,8 @@ type AccountGetRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//   ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//   }
+//   ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//   if(err != nil){ panic(err) }
 //   resp, err := ant.AccountGetAll(nil)
 //   if(err != nil){ panic(err) }
 //   fmt.Println("\n\nAccounts (", resp.Count, "):\n", resp.Header)
@@ -844,98 +954,26 @@ type AccountGetRequest struct{
 //NOTICE:
 //   If you submit something that returns an empty result (like specifying a "creator"
 //   that didn't create any accounts), the API might return an error 90.  This is a
-//   bug in the API that has not been worked-around in this package.
-func (api *Host) AccountGetAll(arg interface{}) (result *accountGetAllResponse, err error){
-	ant := api.ant()
-	request     := AccountGetAllRequest{}
-	request, _   = arg.(AccountGetAllRequest) //fail silently in case we got sent a nil.  Otherwise assume we got a good argument.
+//   bug in the API; by default it is turned into an empty list (see QuirkEmptyAccountList).
+func (api *Host) AccountGetAll(arg interface{}) (result *AccountGetAllResponse, err error){
+	request     := accountGetAllRequest(arg)
 	request.op   = "account_get_all" 
-	result       = &accountGetAllResponse{}
-	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(len(request.Creator)>0){ query += "&creator=" + html.EscapeString(request.Creator)}
-	if(len(request.Type)>0){query += "&type=" + html.EscapeString(request.Type)}
-	if(request.ValidFromStart  != time.Time{}){query += "&type=valid_from_start"  + strconv.FormatInt(request.ValidFromStart.Unix(), 10)}
-	if(request.ValidFromEnd    != time.Time{}){query += "&type=valid_from_end"    + strconv.FormatInt(request.ValidFromEnd.Unix(), 10)}
-	if(request.ValidUntilStart != time.Time{}){query += "&type=valid_until_start" + strconv.FormatInt(request.ValidUntilStart.Unix(), 10)}
-	if(request.ValidUntilEnd   != time.Time{}){query += "&type=valid_until_end"   + strconv.FormatInt(request.ValidUntilEnd.Unix(), 10)}
-	if(len(request.Description)>0){query += "&description=" + html.EscapeString(request.Description)}
-	if(len(request.CreatedStart)>0){query += "&created_start=" + html.EscapeString(request.CreatedStart)}
-	if(len(request.CreatedEnd)>0){query += "&created_end=" + html.EscapeString(request.CreatedEnd)}
-	if(len(request.PlanName)>0){query += "&plan_name=" + html.EscapeString(request.PlanName)}
-	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	result       = &AccountGetAllResponse{loc : api.location}
+	
+	err = request.Validate()
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	fields, err := api.request(request.op, request.params(api.location))
+	if( err != nil){ return nil, err }
 	
-	recordIdentifier := regexp.MustCompile(`record_(\d+)`)
-	records          := make([]Account, 0, 0)
-	for _, v := range parsed_body{
-		switch {
-		case v[1] == "header":
-			//fmt.Println(v[2])
-			result.Header = strings.Split(v[2], "|")
-		case recordIdentifier.MatchString(v[1]):
-			account := Account{}
-			line    := strings.Split(v[2], "|")
-			if(len(line) != 17){ return nil, errors.New("Unknown account information (unexpected array length" + strconv.Itoa(len(line)) +").")}
-			account.Type          = line[ 0]
-			account.Creator       = line[ 1]
-			account.UserId        = line[ 2]
-			account.Code          = line[ 3]
-			account.Description   = line[ 4]
-			
-			switch line[5]{
-			case "yes":
-				account.Enable = true
-			default :
-				account.Enable = false
-			}
-			
-			validFrom, err := strconv.ParseInt(line[ 6], 10, 64)
-			if(err != nil){ return nil, err }
-			account.ValidFrom = time.Unix(validFrom, 0)
-			
-			validUntil, err := strconv.ParseInt(line[ 7], 10, 64)
-			if(err != nil){ return nil, err }
-			account.ValidUntil = time.Unix(validUntil, 0)
-			
-	        switch line[8]{
-	        case "on":
-	        	account.LoginLimit = true
-	        default:
-	        	account.LoginLimit = false
-	        }
-			
-			account.LoginMax, err = strconv.ParseInt(line[ 9], 10, 64)
-			if(err != nil){ return nil, err }
-			
-			account.LoginCount, err = strconv.ParseInt(line[10], 10, 64)
-			if(err != nil){ return nil, err }
-			
-			account.SharingMax, err = strconv.ParseInt(line[11], 10, 64)
-			if(err != nil){ return nil, err }
-			
-			account.UserGroupName = line[12]
-			
-			account.CreateTime    = line[13]
-			account.UpdateTime    = line[14]
-			account.Accounting    = line[15]
-			account.BillingId     = line[16]
-			
-			records = append(records, account)
-	 	case v[1] == "count":
-			result.Count, err = strconv.ParseInt(v[2], 10, 64)
-			if(err != nil){ return nil, err }
-		default:
-			//fmt.Println(v[1])
-		}
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	if(request.filtered()){
+		result.Accounts = slices.DeleteFunc(result.Accounts, func(a Account) bool{ return !request.Match(a) })
 	}
-	result.Accounts = records
 	return result, nil
 }
+//Account is one account, as listed by AccountGetAll or read by AccountGet.
 type Account struct{
 	Type    string
 	Creator string
@@ -943,27 +981,149 @@ type Account struct{
 	Code    string
 	Description string
 	Enable      bool
-	ValidFrom   time.Time
-	ValidUntil  time.Time
+	ValidFrom   time.Time //zero if the account has no start
+	ValidUntil  time.Time //zero if it never expires; see Expires
 	LoginLimit  bool
-	LoginMax, LoginCount, SharingMax int64
-	UserGroupName string
-	CreateTime    string
-	UpdateTime    string
+	LoginMax    int64 //0 if unlimited
+	LoginCount, SharingMax int64
+	UserGroupName string //the plan
+	CreateTime    time.Time
+	UpdateTime    time.Time
 	Accounting    string
 	BillingId     string
+	
+	//Only from AccountGet.  The gateway gives the balances in minutes and
+	//bytes, the units auth_update changes them in, or as "unlimited"
+	//(UnlimitedDuration, UnlimitedVolume); a blank balance is 0.
+	Sharing         []Sharing
+	DurationBalance time.Duration
+	VolumeBalance   ByteSize
+}
+
+//Sharing is one of the devices sharing an account; ClientMac is nil for an
+//unused slot.
+type Sharing struct{
+	Index     int64
+	ClientMac net.HardwareAddr
 }
-type accountGetAllResponse struct{
-	responseCommon
+//AccountGetAllResponse is the reply to op=account_get_all.  Count is the
+//number of accounts the gateway listed; Accounts holds fewer when the request
+//filtered on the reply (see AccountGetAllRequest.Match).
+type AccountGetAllResponse struct{
+	ResponseCommon
 	Count int64
 	Header []string
 	Accounts []Account
+	loc    *time.Location //see WithLocation
+}
+func (result *AccountGetAllResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	records := make([]Account, 0, 0)
+	for _, v := range fields{
+		switch {
+		case v.Name == "header":
+			//fmt.Println(v.Value)
+			result.Header = strings.Split(v.Value, "|")
+		case isRecord(v.Name):
+			account, err := decodeAccountRecord(v, result.loc)
+			if(err != nil){ return err }
+			records = append(records, account)
+	 	case v.Name == "count":
+			result.Count, err = strconv.ParseInt(v.Value, 10, 64)
+			if(err != nil){ return parseErr(v, err) }
+		default:
+			//fmt.Println(v.Name)
+		}
+	}
+	result.Accounts = records
+	return nil
 }
+
+//DecodeAccountRecord decodes one record_N field of an account_get_all reply.
+//The record is a pipe separated list of the 17 columns named in the header.
+//Its times are read as the gateway's in time.Local; see WithLocation.
+func DecodeAccountRecord(v antlabs.Field) (account Account, err error){ return decodeAccountRecord(v, nil) }
+
+func decodeAccountRecord(v antlabs.Field, loc *time.Location) (account Account, err error){
+	line := strings.Split(v.Value, "|")
+	if(len(line) != 17){ return account, parseErr(v, errors.New("Unknown account information (unexpected array length " + strconv.Itoa(len(line)) +")."))}
+	account.Type          = line[ 0]
+	account.Creator       = line[ 1]
+	account.UserId        = line[ 2]
+	account.Code          = line[ 3]
+	account.Description   = line[ 4]
+	
+	switch line[5]{
+	case "yes":
+		account.Enable = true
+	default :
+		account.Enable = false
+	}
+	
+	account.ValidFrom, err = ParseTime(line[ 6], loc)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.ValidUntil, err = ParseTime(line[ 7], loc)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	switch line[8]{
+	case "on":
+		account.LoginLimit = true
+	default:
+		account.LoginLimit = false
+	}
+	
+	account.LoginMax, err = strconv.ParseInt(line[ 9], 10, 64)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.LoginCount, err = strconv.ParseInt(line[10], 10, 64)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.SharingMax, err = strconv.ParseInt(line[11], 10, 64)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.UserGroupName = line[12]
+	
+	account.CreateTime, err = ParseTime(line[13], loc)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.UpdateTime, err = ParseTime(line[14], loc)
+	if(err != nil){ return account, parseErr(v, err) }
+	
+	account.Accounting    = line[15]
+	account.BillingId     = line[16]
+	
+	return account, nil
+}
+
+//isRecord reports whether name is one of the record_N fields that carry
+//the rows of account_get_all and plan_get_all replies.
+func isRecord(name string) (bool){
+	if(!strings.HasPrefix(name, "record_") || len(name) == len("record_")){ return false }
+	for _, c := range name[len("record_"):]{
+		if(c < '0' || c > '9'){ return false }
+	}
+	return true
+}
+//AccountGetAllRequest filters the accounts listed by AccountGetAll; see also
+//AccountQuery.  Zero fields do not filter, and a zero time leaves its end of
+//a range open.
 type AccountGetAllRequest struct{
 	requestCommon
+	//Sent to the gateway:
 	ValidFromStart, ValidFromEnd, ValidUntilStart, ValidUntilEnd time.Time
-	Creator, Description, Type string
-	CreatedStart, CreatedEnd, PlanName string
+	Creator     string
+	Description string //matches part of the description
+	Type AccountType
+	CreatedStart, CreatedEnd time.Time //sent on the gateway's clock; see WithLocation
+	PlanName string
+	
+	//Checked on the reply, as the gateway cannot filter on them (see Match):
+	Enabled                      Optional[bool]
+	LoginCountMin, LoginCountMax Optional[int64]
+	UserGroupName                string //exactly; see AccountQuery.UserGroup
 }
 //////////////////////////////////////////////////////////
 
@@ -974,9 +1134,8 @@ type AccountGetAllRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AccountDelete(innGateApi.AccountDeleteRequest{Code : "abc123"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nDeleted:", resp.Deleted)
@@ -990,45 +1149,54 @@ type AccountGetAllRequest struct{
 //  the database, it will return an error with resultcode 98 (database error).  If it finds at least one
 //  match, the request should reply with success.  Furthermore, the ANTLabs database/API seems bugarrific; 
 //  frequently an account can be seen through the admin portal, but not found when making an API request.
-//  If a database error occurs with the API, that result will be passed along.
-func (api *Host) AccountDelete(request AccountDeleteRequest) (result *accountDeleteResponse, err error){
-	ant := api.ant()
+//  If a database error occurs with the API, that result will be passed along, except that by
+//  default a 98 is read as "nothing matched" and Deleted is 0 (see QuirkDeleteNoMatch).
+func (api *Host) AccountDelete(request AccountDeleteRequest) (result *AccountDeleteResponse, err error){
 	request.op = "account_delete" 
-	result     = &accountDeleteResponse{}
+	result     = &AccountDeleteResponse{}
 	
-	query := "api_password="+api.Pass+"&op="+request.op
+	err = request.Validate()
+	if( err != nil){ return nil, err }
+	
+	params := url.Values{}
 	switch request.Code.(type){
 	case string:
-		if(request.Code != ""){query += "&code=" + request.Code.(string)}
+		if(request.Code != ""){params.Set("code", request.Code.(string))}
 	case []string:
-		if(len(request.Code.([]string)) > 0){query += "&code=" + strings.Join(request.Code.([]string), "|")}
+		if(len(request.Code.([]string)) > 0){params.Set("code", strings.Join(request.Code.([]string), "|"))}
 	}
 	switch request.UserId.(type){
 	case string:
-		if(request.UserId != ""){query += "&userid=" + request.UserId.(string)}
+		if(request.UserId != ""){params.Set("userid", request.UserId.(string))}
 	case []string:
-		if(len(request.UserId.([]string)) > 0){query += "&userid=" + strings.Join(request.UserId.([]string), "|")}
+		if(len(request.UserId.([]string)) > 0){params.Set("userid", strings.Join(request.UserId.([]string), "|"))}
 	}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//AccountDeleteResponse is the reply to op=account_delete.
+type AccountDeleteResponse struct{
+	ResponseCommon
+	Deleted int64
+}
+func (result *AccountDeleteResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 	 	case "deleted":
-			result.Deleted, err = strconv.ParseInt(v[2], 10, 64)
-			if(err != nil){ return nil, err }
+			result.Deleted, err = strconv.ParseInt(v.Value, 10, 64)
+			if(err != nil){ return parseErr(v, err) }
 		}
 	}
 	
-	return result, nil
-}
-type accountDeleteResponse struct{
-	responseCommon
-	Deleted int64
+	return nil
 }
 type AccountDeleteRequest struct{
 	requestCommon
@@ -1043,72 +1211,86 @@ type AccountDeleteRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.AuthLogin(innGateApi.AuthLoginRequest{Sid : "86cb1a5deb036467a9c2bc36e13971ef"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nLogin result:", resp.Result)
-func (api *Host) AccountUpdate(request AccountUpdateRequest) (result *accountUpdateResponse, err error){
-	ant := api.ant()
+func (api *Host) AccountUpdate(request AccountUpdateRequest) (result *AccountUpdateResponse, err error){
 	request.op = "account_update" 
-	result     = &accountUpdateResponse{}
-	
-	query := "api_password="+api.Pass+"&op="+request.op
-	if(request.Password != ""){ query += "&password=" + html.EscapeString(request.Password) }
-	if(request.PasswordLength >0){ query += "&password_length=" + strconv.FormatInt(request.PasswordLength, 10)}
-	if(request.PasswordFormat != ""){ query += "&password_format=" + request.PasswordFormat }
-	if(request.Description != ""){ query += "&description=" + request.Description }
-	if(request.ValidFrom != time.Time{}){ query += "&valid_from=" + strconv.FormatInt(request.ValidFrom.Unix(), 10)}
-	if(request.ValidUntil != time.Time{}){ query += "&valid_until=" + strconv.FormatInt(request.ValidUntil.Unix(), 10)}
-	if(request.LoginLimit){
-		query += "&login_limit=on"
-	}else{
-		query += "&login_limit=off"
-	}
-	if(request.LoginMax > 0){ query += "&login_max=" + strconv.FormatInt(request.LoginMax, 10) }
-	if(request.SharingMax > 0){ query += "&sharing_max=" + strconv.FormatInt(request.SharingMax, 10) }
-	if(request.PlanId > 0){ query += "&plan_id=" + strconv.FormatInt(request.PlanId, 10) }
-	if(request.PlanName != ""){ query += "&plan_name=" + request.PlanName }
-	if(request.AllowedLoginZone > 0){ query += "&allowed_login_zone=" + strconv.FormatInt(request.AllowedLoginZone, 10) }
+	result     = &AccountUpdateResponse{}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	err = request.Validate()
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
-	
-	for _, v := range parsed_body{
-		switch v[1]{
-		case "password":
-			result.Password = v[2]
+	params := url.Values{}
+	if(request.UserId != ""){ params.Set("userid", request.UserId) }
+	if(request.Code != ""){ params.Set("code", request.Code) }
+	if v, ok := request.Password.Get(); ok{ params.Set("password", v) }
+	if(request.PasswordLength >0){ params.Set("password_length", strconv.FormatInt(request.PasswordLength, 10))}
+	if(request.PasswordFormat != FormatDefault){ params.Set("password_format", request.PasswordFormat.String()) }
+	if v, ok := request.Description.Get(); ok{ params.Set("description", v) }
+	if t, ok := request.ValidFrom.Get(); ok{ params.Set("valid_from", formatTime(t)) }
+	if t, ok := request.ValidUntil.Get(); ok{ params.Set("valid_until", formatTime(t)) }
+	if(request.LoginMax.IsUnlimited()){ request.LoginLimit = Bool(false) }
+	if on, ok := request.LoginLimit.Get(); ok{
+		if(on){
+			params.Set("login_limit", "on")
+		}else{
+			params.Set("login_limit", "off")
 		}
 	}
+	if n, ok := request.LoginMax.Max(); ok{ params.Set("login_max", strconv.FormatInt(n, 10)) }
+	if(request.SharingMax > 0){ params.Set("sharing_max", strconv.FormatInt(request.SharingMax, 10)) }
+	planId, ok, err := api.resolvePlan(request.PlanId, request.PlanName)
+	if( err != nil){ return nil, err }
+	if(ok){ params.Set("plan_id", strconv.FormatInt(planId, 10)) }
+	if n, ok := request.AllowedLoginZone.Get(); ok{ params.Set("allowed_login_zone", strconv.FormatInt(n, 10)) }
 	
+	fields, err := api.request(request.op, params)
+	if( err != nil){ return nil, err }
+	
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
 	return result, nil
 }
-type accountUpdateResponse struct{
-	responseCommon
+//AccountUpdateResponse is the reply to op=account_update.  Password is set
+//only when the update generated a new password.
+type AccountUpdateResponse struct{
+	ResponseCommon
 	Password  string
 }
+func (result *AccountUpdateResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	for _, v := range fields{
+		switch v.Name{
+		case "password":
+			result.Password = v.Value
+		}
+	}
+	
+	return nil
+}
 type AccountUpdateRequest struct{
 	requestCommon
 	//Required:
 	UserId           string
 	//or:
 	Code             string
-	//Optional:
-	Password         string
+	//Optional; only the fields set are changed:
+	Password         Optional[string] //Set("") generates a new password
 	PasswordLength   int64
-	PasswordFormat   string //alpha|alnum|num (default alnum)
-	Description      string
-	ValidUntil       time.Time
-	ValidFrom        time.Time
-	LoginLimit       bool
-	LoginMax         int64
+	PasswordFormat   Format //(default alnum)
+	Description      Optional[string]
+	ValidUntil       Optional[time.Time] //NoTime() removes it: no expiry
+	ValidFrom        Optional[time.Time] //NoTime() removes it
+	LoginLimit       Optional[bool]
+	LoginMax         LoginMax //UnlimitedLogins turns LoginLimit off
 	SharingMax       int64
-	AllowedLoginZone int64
-	//If account has never logged in (optional):
+	AllowedLoginZone Optional[int64]
+	//If account has never logged in (optional; checked as for AccountAddRequest):
 	PlanId         int64
 	//or:
 	PlanName       string
@@ -1122,52 +1304,60 @@ type AccountUpdateRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.PublicIp(innGateApi.PublicIpRequest{Sid : "86cb1a5deb036467a9c2bc36e13971ef"})
 //  if(err != nil){ panic(err) }
-//  fmt.Println("IP:", resp.Ip)
-func (api *Host) PublicIp(request PublicIpRequest) (result *publicIpResponse, err error){
-	ant := api.ant()
+//  fmt.Println("IP:", resp.PublicIp)
+func (api *Host) PublicIp(request PublicIpRequest) (result *PublicIpResponse, err error){
 	request.op = "publicip_get"
-	result     = &publicIpResponse{}
+	result     = &PublicIpResponse{}
+	
+	err = request.Validate()
+	if( err != nil){ return nil, err }
 	
-	query := "api_password="+api.Pass+"&op="+request.op
+	params := url.Values{}
 	if(request.Sid != ""){ 
-		query += "&sid=" + request.Sid
+		params.Set("sid", request.Sid)
 	}else{
-		query += "&client_mac=" + html.EscapeString(request.ClientMac)
-		query += "&ppli=" + request.Ppli
+		params.Set("client_mac", macString(request.ClientMac))
+		params.Set("ppli", request.Ppli)
 	}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//PublicIpResponse is the reply to op=publicip_get.
+type PublicIpResponse struct{
+	ResponseCommon
+	PublicIp       netip.Addr
+}
+func (result *PublicIpResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
+	for _, v := range fields{
 		//TODO: API does not indicate a field that returns the IP.  Need testing with a site that gives out Public IPs.
-		switch v[1]{
+		switch v.Name{
 		case "public_ip":
-			result.PublicIp = v[2]
+			result.PublicIp, err = decodeIp(v, v.Value)
+			if(err != nil){ return err }
 	 	default: 
-	 		//fmt.Println("unknown key: " + v[1])
+	 		//fmt.Println("unknown key: " + v.Name)
 	 	}
 	}
 	
-	return result, nil
-}
-type publicIpResponse struct{
-	responseCommon
-	PublicIp       string
-	
+	return nil
 }
 type PublicIpRequest struct{
 	requestCommon
 	Sid string
-	ClientMac, Ppli string
+	ClientMac net.HardwareAddr
+	Ppli      string
 }
 //////////////////////////////////////////////////////////
 
@@ -1176,9 +1366,8 @@ type PublicIpRequest struct{
 //  No optional or required arguments.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.ApiVersion()
 //  if(err != nil){ panic(err) }
 //  fmt.Println("\n\nAPI_Version:", resp.ApiVersion)
@@ -1187,31 +1376,36 @@ type PublicIpRequest struct{
 //  instead of just "Version."  But this comes from the op, and we chose
 //  to keep it in long form so the result.ApiVersion is distinct from
 //  the common version (of the op, not the API).
-func (api *Host) ApiVersion() (result *versionResponse, err error){
-	ant := api.ant()
+func (api *Host) ApiVersion() (result *VersionResponse, err error){
 	request     := versionRequest{}
 	request.op   = "api_version" 
-	result       = &versionResponse{}
+	result       = &VersionResponse{}
 	
-	parsed_body, err := ant.InnGateApiRequest("api_password="+api.Pass+"&op="+request.op)
+	fields, err := api.request(request.op, nil)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//VersionResponse is the reply to op=api_version.
+type VersionResponse struct{
+	ResponseCommon
+	ApiVersion float64
+}
+func (result *VersionResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
 	
-	for _, v := range parsed_body{
-		switch v[1]{
+	for _, v := range fields{
+		switch v.Name{
 	 	case "api_version":
-			result.ApiVersion, err = strconv.ParseFloat(v[2], 64)
-			if(err != nil){ return nil, err }
+			result.ApiVersion, err = strconv.ParseFloat(v.Value, 64)
+			if(err != nil){ return parseErr(v, err) }
 		}
 	}
 	
-	return result, nil
-}
-type versionResponse struct{
-	responseCommon
-	ApiVersion float64
+	return nil
 }
 type versionRequest struct{
 	requestCommon
@@ -1224,63 +1418,23 @@ type versionRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.PlanAll()
 //  if(err != nil){ panic(err) }
 //  fmt.Println("Result:", resp.Result)
-func (api *Host) PlanAll() (result *planAllResponse, err error){
-	ant := api.ant()
+func (api *Host) PlanAll() (result *PlanAllResponse, err error){
 	request   := planAllRequest{}
 	request.op = "plan_get_all"
-	result     = &planAllResponse{}
+	result     = &PlanAllResponse{}
 	
-	query := "api_password="+api.Pass+"&op="+request.op
+	params := url.Values{}
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
-	
-	recordIdentifier := regexp.MustCompile(`record_(\d+)`)
-	records          := make([]Plan, 0, 0)
-	for _, v := range parsed_body{
-		switch {
-		case recordIdentifier.MatchString(v[1]):
-			plan := Plan{}
-			line    := strings.Split(v[2], "|")
-			if(len(line) != 18){ return nil, errors.New("Unknown account information (unexpected array length" + strconv.Itoa(len(line)) +").")}
-			plan.Id, err                = strconv.ParseInt(line[ 0], 10, 64)
-			if(err != nil){ return nil, err }
-			plan.Price                  = line[ 1]
-			plan.AuthenticationType     = line[ 2]
-			if(line[ 3] == "on"){ plan.DurationLimit = true }
-			plan.ValidDuration, err     = strconv.ParseInt(line[ 4], 10, 64)
-			if(err != nil){ return nil, err }
-			if(line[ 5] == "on"){ plan.VolumeLimit = true }
-			plan.ValidVolume, err       = strconv.ParseInt(line[ 6], 10, 64)
-			if(err != nil){ return nil, err }
-			plan.VolumeExpiredAction    = line[ 7]
-			if(line[ 8] == "on"){ plan.DownloadLimit = true }
-			plan.DownloadBandwidth, err = strconv.ParseInt(line[ 9], 10, 64)
-			plan.DownloadUnits          = line[10]
-			if(line[11] == "on"){ plan.UploadLimit = true }
-			plan.UploadBandwidth, err   = strconv.ParseInt(line[12], 10, 64)
-			plan.UploadUnit             = line[13]
-			plan.PublicIp               = line[14]
-			if(line[15] == "on"){ plan.Relogin = true }
-			if(line[16] == "on"){ plan.FairUse = true }
-			plan.Name                   = line[17]
-
-			records = append(records, plan)
-		default:
-			//fmt.Println(v[1])
-		}
-	}
-	result.Plans = records
-	
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
 	return result, nil
 }
 type Plan struct{
@@ -1288,9 +1442,9 @@ type Plan struct{
 	Price               string
 	AuthenticationType  string
 	DurationLimit       bool
-	ValidDuration       int64  //in minutes
+	ValidDuration       time.Duration //UnlimitedDuration unless DurationLimit
 	VolumeLimit         bool
-	ValidVolume         int64  //in MB
+	ValidVolume         ByteSize      //UnlimitedVolume unless VolumeLimit
 	VolumeExpiredAction string
 	DownloadLimit       bool
 	DownloadBandwidth   int64
@@ -1303,10 +1457,64 @@ type Plan struct{
 	FairUse             bool
 	Name            string
 }
-type planAllResponse struct{
-	responseCommon
+//PlanAllResponse is the reply to op=plan_get_all.
+type PlanAllResponse struct{
+	ResponseCommon
 	Plans           []Plan
 }
+func (result *PlanAllResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	
+	records := make([]Plan, 0, 0)
+	for _, v := range fields{
+		switch {
+		case isRecord(v.Name):
+			plan, err := DecodePlanRecord(v)
+			if(err != nil){ return err }
+			records = append(records, plan)
+		default:
+			//fmt.Println(v.Name)
+		}
+	}
+	result.Plans = records
+	
+	return nil
+}
+
+//DecodePlanRecord decodes one record_N field of a plan_get_all reply, a pipe
+//separated list of the 18 plan fields in the order documented by ANTLabs.
+func DecodePlanRecord(v antlabs.Field) (plan Plan, err error){
+	line := strings.Split(v.Value, "|")
+	if(len(line) != 18){ return plan, parseErr(v, errors.New("Unknown plan information (unexpected array length " + strconv.Itoa(len(line)) +")."))}
+	plan.Id, err                = strconv.ParseInt(line[ 0], 10, 64)
+	if(err != nil){ return plan, parseErr(v, err) }
+	plan.Price                  = line[ 1]
+	plan.AuthenticationType     = line[ 2]
+	if(line[ 3] == "on"){ plan.DurationLimit = true }
+	plan.ValidDuration, err     = parseDuration(line[ 4])
+	if(err != nil){ return plan, parseErr(v, err) }
+	if(!plan.DurationLimit){ plan.ValidDuration = UnlimitedDuration }
+	if(line[ 5] == "on"){ plan.VolumeLimit = true }
+	plan.ValidVolume, err       = parseVolume(line[ 6], Megabyte)
+	if(err != nil){ return plan, parseErr(v, err) }
+	if(!plan.VolumeLimit){ plan.ValidVolume = UnlimitedVolume }
+	plan.VolumeExpiredAction    = line[ 7]
+	if(line[ 8] == "on"){ plan.DownloadLimit = true }
+	plan.DownloadBandwidth, err = strconv.ParseInt(line[ 9], 10, 64)
+	if(err != nil){ return plan, parseErr(v, err) }
+	plan.DownloadUnits          = line[10]
+	if(line[11] == "on"){ plan.UploadLimit = true }
+	plan.UploadBandwidth, err   = strconv.ParseInt(line[12], 10, 64)
+	if(err != nil){ return plan, parseErr(v, err) }
+	plan.UploadUnit             = line[13]
+	plan.PublicIp               = line[14]
+	if(line[15] == "on"){ plan.Relogin = true }
+	if(line[16] == "on"){ plan.FairUse = true }
+	plan.Name                   = line[17]
+	
+	return plan, nil
+}
 type planAllRequest struct{
 	requestCommon
 }
@@ -1319,38 +1527,45 @@ type planAllRequest struct{
 //  The example below demonstrates how to send a request.
 //
 //Example: 
-//  ant := innGateApi.Host{ 
-// 	   Host : "ant.example.com", //can be an IP or hostname
-//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret")) //can be an IP or hostname
+//  if(err != nil){ panic(err) }
 //  resp, err := ant.PlanId(innGateApi.PlanIdRequest{Name : "Guest"})
 //  if(err != nil){ panic(err) }
 //  fmt.Println("Id:", resp.Id)
-func (api *Host) PlanId(request PlanIdRequest) (result *planIdResponse, err error){
-	ant := api.ant()
+func (api *Host) PlanId(request PlanIdRequest) (result *PlanIdResponse, err error){
 	request.op = "plan_get_id"
-	result     = &planIdResponse{}
+	result     = &PlanIdResponse{}
 	
-	query := "api_password="+api.Pass+"&op="+request.op
-	query += "&plan_name=" + request.Name
+	err = request.Validate()
+	if( err != nil){ return nil, err }
+	
+	params := url.Values{}
+	params.Set("plan_name", request.Name)
 	
-	parsed_body, err := ant.InnGateApiRequest(query)
+	fields, err := api.request(request.op, params)
 	if( err != nil){ return nil, err }
 	
-	err = result.findCommoners(parsed_body)
-	//if( err != nil){ return nil, err }
-	for _, v := range parsed_body{
-		switch v[1]{
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
+//PlanIdResponse is the reply to op=plan_get_id.
+type PlanIdResponse struct{
+	ResponseCommon
+	Id             int64
+}
+func (result *PlanIdResponse) decode(fields []antlabs.Field) (err error){
+	err = result.findCommoners(fields)
+	if( err != nil){ return err }
+	for _, v := range fields{
+		switch v.Name{
 		case "plan_id":
-			result.Id, err = strconv.ParseInt(v[2], 10, 64)
-			if( err != nil){ return nil, err }
+			result.Id, err = strconv.ParseInt(v.Value, 10, 64)
+			if( err != nil){ return parseErr(v, err) }
 		}
 	}
 	
-	return result, nil
-}
-type planIdResponse struct{
-	responseCommon
-	Id             int64
+	return nil
 }
 type PlanIdRequest struct{
 	requestCommon
@@ -1358,18 +1573,43 @@ type PlanIdRequest struct{
 }
 //////////////////////////////////////////////////////////
 
-//ant = api.ant() is called at the beginning of every API method, which
-//provides invisible glue between the innGateApi package and the antlabs package.
-func (api *Host) ant() (ant *antlabs.Host){
-	//Let's start by defining defaults in case these arguments weren't specified.
-	port := api.Port; if(port == 0 ){ port = 443; api.Port = port }
-	pass := api.Pass; if(pass == ""){ pass = "admin"; api.Pass = pass }
-	
-	//And now we prepare our return.
-	ant = &antlabs.Host{
-		Host    : api.Host,
-		Port    : port,
-		ApiPass : pass,
+//decoder is implemented by every response type.
+type decoder interface{
+	decode(fields []antlabs.Field) (err error)
+}
+
+//Decode decodes body, the raw reply to op, into the response type that the
+//method for op returns; e.g. Decode("plan_get_all", body) yields the same
+//value PlanAll would have.  This is useful for replaying replies captured
+//from a gateway.  Replies that cannot be decoded yield an *antlabs.ParseError.
+func Decode(op, body string) (response interface{}, err error){
+	var result decoder
+	switch op{
+	case "api_module":        result = &ModuleResponse{}
+	case "api_modules":       result = &ModulesResponse{}
+	case "api_version":       result = &VersionResponse{}
+	case "auth_authenticate": result = &AuthAuthenticateResponse{}
+	case "auth_login":        result = &AuthLoginResponse{}
+	case "auth_logout":       result = &AuthLogoutResponse{}
+	case "auth_init":         result = &AuthInitResponse{}
+	case "auth_update":       result = &AuthUpdateResponse{}
+	case "sid_get":           result = &SidGetResponse{}
+	case "publicip_get":      result = &PublicIpResponse{}
+	case "account_add":       result = &AccountAddResponse{}
+	case "account_get":       result = &AccountGetResponse{}
+	case "account_get_all":   result = &AccountGetAllResponse{}
+	case "account_delete":    result = &AccountDeleteResponse{}
+	case "account_update":    result = &AccountUpdateResponse{}
+	case "plan_get_all":      result = &PlanAllResponse{}
+	case "plan_get_id":       result = &PlanIdResponse{}
+	default:
+		return nil, errors.New("Unknown op " + strconv.Quote(op))
 	}
-	return
-}
\ No newline at end of file
+	
+	fields, err := antlabs.ParseApiResponse(body)
+	if( err != nil){ return nil, err }
+	
+	err = result.decode(fields)
+	if( err != nil){ return nil, err }
+	return result, nil
+}
@@ -0,0 +1,68 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs"
+	"github.com/secesh/gantlabs/innGate"
+	"errors"
+	"testing"
+)
+
+//TestDecodeAccountGet decodes an account_get reply for two codes, the first
+//shared by two devices: each code is one Account, with a Sharing entry per
+//device.
+func TestDecodeAccountGet(t *testing.T){
+	body := "op = account_get\nversion = 1.0\nresult = ok\nresultcode = 0\n" +
+		"userid = abcde|abcde|fghij\ncode = k2m4p|k2m4p|q8r7s\nsharing_index = 0|1|0\n" +
+		"client_mac = 00:11:25:87:0B:7D||00:11:25:87:0B:7E\ndescription = lobby|lobby|pool\nenabled = yes|yes|no\n" +
+		"valid_from = ||\nvalid_until = ||\nlogin_limit = off|off|on\nlogin_max = 0|0|3\nlogin_count = 1|1|0\nsharing_max = 2|2|1\n" +
+		"plan = Guest|Guest|Throttled\nduration_balance = unlimited|unlimited|unlimited\nvolume_balance = unlimited|unlimited|unlimited\n" +
+		"create_time = Thu, 25 Jun 2009 14:59:00 +0800|Thu, 25 Jun 2009 14:59:00 +0800|Fri, 26 Jun 2009 09:00:00 +0800\n" +
+		"update_time = Thu, 25 Jun 2009 14:59:00 +0800|Thu, 25 Jun 2009 14:59:00 +0800|Fri, 26 Jun 2009 09:00:00 +0800\n"
+	decoded, err := innGateApi.Decode("account_get", body)
+	if(err != nil){ t.Fatal(err) }
+	accounts := decoded.(*innGateApi.AccountGetResponse).Accounts
+	if(len(accounts) != 2){ t.Fatalf("got %d accounts, want 2: %+v", len(accounts), accounts) }
+	
+	shared, single := accounts[0], accounts[1]
+	if(shared.UserId != "abcde" || shared.Code != "k2m4p" || shared.Description != "lobby" || !shared.Enable || shared.UserGroupName != "Guest"){
+		t.Errorf("first account: got %+v", shared)
+	}
+	if(len(shared.Sharing) != 2 || shared.Sharing[0].Index != 0 || shared.Sharing[0].ClientMac.String() != "00:11:25:87:0b:7d" ||
+		shared.Sharing[1].Index != 1 || shared.Sharing[1].ClientMac != nil){
+		t.Errorf("first account: got sharing %+v, want index 0 on 00:11:25:87:0b:7d and index 1 unused", shared.Sharing)
+	}
+	if(single.UserId != "fghij" || single.Code != "q8r7s" || single.Enable || !single.LoginLimit || single.LoginMax != 3 || single.UserGroupName != "Throttled"){
+		t.Errorf("second account: got %+v", single)
+	}
+	if(len(single.Sharing) != 1 || single.Sharing[0].ClientMac.String() != "00:11:25:87:0b:7e"){
+		t.Errorf("second account: got sharing %+v, want one device", single.Sharing)
+	}
+	if(!shared.ValidFrom.IsZero() || !shared.ValidUntil.IsZero()){ t.Errorf("blank valid_from and valid_until decoded as %v and %v", shared.ValidFrom, shared.ValidUntil) }
+}
+
+//TestDecodeAccountGetBadMac decodes an account_get reply whose client_mac is
+//not a MAC address.
+func TestDecodeAccountGetBadMac(t *testing.T){
+	body := "op = account_get\nversion = 1.0\nresult = ok\nresultcode = 0\nuserid = abcde\ncode = k2m4p\nsharing_index = 0\nclient_mac = 00:11:25\n"
+	_, err := innGateApi.Decode("account_get", body)
+	var perr *antlabs.ParseError
+	if(!errors.As(err, &perr) || perr.Field != "client_mac"){ t.Errorf("got %T (%v), want a ParseError on client_mac", err, err) }
+}
@@ -0,0 +1,162 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"github.com/secesh/gantlabs"
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+//DefaultCacheTTLs are the ops NewCache caches when given no TTLs: those whose
+//answers change rarely.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"plan_get_all" : 5*time.Minute,
+	"plan_get_id"  : 5*time.Minute,
+	"api_modules"  : time.Hour,
+	"api_module"   : time.Hour,
+	"api_version"  : time.Hour,
+}
+
+//Cache keeps successful replies to read-only ops for a per-op TTL.
+//Concurrent identical calls that miss the cache share a single request to
+//the gateway, which goes on until it is answered even if the caller that
+//started it gives up; each caller waits only as long as its own context.  Install it with WithCache; it is safe for concurrent use and
+//may be shared by several Hosts talking to the same gateway.
+//
+//Example:
+//  cache := innGateApi.NewCache(nil)
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"), innGateApi.WithCache(cache))
+//  ...
+//  cache.Invalidate("plan_get_all", "plan_get_id") //plans were edited
+type Cache struct{
+	ttls    map[string]time.Duration
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct{
+	op       string
+	done     chan struct{} //closed once reply/err are set
+	reply    *Reply
+	err      error
+	attempts int //times the request was sent
+	expires  time.Time
+}
+
+//NewCache returns a Cache for the ops in ttls, or DefaultCacheTTLs if ttls is
+//nil.  Ops not listed are never cached.
+func NewCache(ttls map[string]time.Duration) (c *Cache){
+	if(ttls == nil){ ttls = DefaultCacheTTLs }
+	c = &Cache{ttls : make(map[string]time.Duration, len(ttls)), entries : make(map[string]*cacheEntry)}
+	for op, ttl := range ttls{ c.ttls[op] = ttl }
+	return c
+}
+
+//WithCache answers cacheable ops from c.  It is installed after the
+//middleware from WithMiddleware, so cache hits never reach the gateway,
+//discovery, metrics or quirks.
+func WithCache(c *Cache) (Option){ return func(api *Host){ api.cache = c } }
+
+//Invalidate drops the cached replies for ops, or for every op if none are
+//given.
+func (c *Cache) Invalidate(ops ...string){
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if(len(ops) == 0){
+		c.entries = make(map[string]*cacheEntry)
+		return
+	}
+	drop := make(map[string]bool, len(ops))
+	for _, op := range ops{ drop[op] = true }
+	for key, e := range c.entries{
+		if(drop[e.op]){ delete(c.entries, key) }
+	}
+}
+
+//Middleware returns the caching middleware; WithCache installs it.
+func (c *Cache) Middleware() (Middleware){
+	return func(next Invoker) Invoker{
+		return func(ctx context.Context, call *Call) (*Reply, error){
+			ttl, ok := c.ttls[call.Op]
+			if(!ok || ttl <= 0 || call.stream != nil){ return next(ctx, call) }
+			key := call.Op + "?" + call.Params.Encode()
+			
+			c.mu.Lock()
+			e := c.entries[key]
+			if(e != nil){
+				select{
+				case <-e.done:
+					if(time.Now().After(e.expires)){
+						delete(c.entries, key)
+						e = nil
+					}
+				default:
+				}
+			}
+			leader := e == nil
+			if(leader){
+				e = &cacheEntry{op : call.Op, done : make(chan struct{})}
+				c.entries[key] = e
+				go c.fetch(context.WithoutCancel(ctx), next, call, key, ttl, e)
+			}
+			c.mu.Unlock()
+			
+			//Every caller, the one that started the request included, stops
+			//waiting only when its own ctx ends; the request goes on for the
+			//others.
+			select{
+			case <-e.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if(leader){ call.Attempt = e.attempts }
+			return e.reply.clone(), e.err
+		}
+	}
+}
+
+//fetch sends the call that first missed key, on a context no caller can
+//cancel, and publishes the answer to everyone waiting on e.  It sends a copy
+//of call, so that a caller that stops waiting does not see it change.
+func (c *Cache) fetch(ctx context.Context, next Invoker, call *Call, key string, ttl time.Duration, e *cacheEntry){
+	shared := &Call{Op : call.Op, Params : url.Values{}, Attempt : call.Attempt}
+	for k, v := range call.Params{ shared.Params[k] = append([]string(nil), v...) }
+	reply, err := next(ctx, shared)
+	
+	c.mu.Lock()
+	e.reply, e.err, e.attempts, e.expires = reply.clone(), err, shared.Attempt, time.Now().Add(ttl)
+	if(err != nil || reply.code() != "0"){
+		//only successes are kept; waiters still get this answer
+		if(c.entries[key] == e){ delete(c.entries, key) }
+	}
+	c.mu.Unlock()
+	close(e.done)
+}
+
+//clone copies reply so that cached replies are not changed by their users.
+func (reply *Reply) clone() (*Reply){
+	if(reply == nil){ return nil }
+	c := *reply
+	c.Fields = append([]antlabs.Field(nil), reply.Fields...)
+	return &c
+}
@@ -0,0 +1,71 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+//TestCacheLeaderCancelled cancels the caller whose cache miss started a
+//plan_get_all while the gateway is still answering.  That caller gives up,
+//but a second caller sharing the request must still get the plans, from the
+//same single request.
+func TestCacheLeaderCancelled(t *testing.T){
+	var requests atomic.Int32
+	arrived, release := make(chan struct{}, 1), make(chan struct{})
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.HandleOp("plan_get_all", func(w http.ResponseWriter, r *http.Request){
+		requests.Add(1)
+		arrived <- struct{}{}
+		<-release
+		fmt.Fprint(w, "op = plan_get_all\nversion = 1.0\nresult = ok\nresultcode = 0\n" +
+			"record_1 = 4|0.00|unlimited|off|0|off|0|logout|on|256|kbps|on|128|kbps|off|off|off|Throttled\n")
+	})
+	ant := gw.Host(innGateApi.WithCache(innGateApi.NewCache(nil)))
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func(){
+		_, err := ant.WithContext(ctx).PlanAll()
+		leader <- err
+	}()
+	<-arrived
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled){ t.Fatalf("the cancelled caller got %v, want context.Canceled", err) }
+	
+	waiter := make(chan *innGateApi.PlanAllResponse)
+	go func(){
+		resp, err := ant.PlanAll()
+		if(err != nil){ t.Error(err) }
+		waiter <- resp
+	}()
+	close(release)
+	resp := <-waiter
+	if(resp == nil || len(resp.Plans) != 1){ t.Fatalf("got %+v, want the plan", resp) }
+	if n := requests.Load(); n != 1{ t.Errorf("the gateway got %d requests, want 1", n) }
+}
@@ -0,0 +1,141 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"github.com/secesh/gantlabs"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+//ErrUnsupported is returned, wrapped, for an op the gateway does not have,
+//or has in a version older than required (see WithDiscovery).
+var ErrUnsupported = errors.New("innGateApi: op not supported by the gateway")
+
+//Capabilities is the set of API modules installed on a gateway, by name
+//(= op), with their versions, as reported by api_modules.
+type Capabilities map[string]float64
+
+//Has reports whether the gateway has the module for op.
+func (c Capabilities) Has(op string) (bool){
+	_, ok := c[op]
+	return ok
+}
+
+//AtLeast reports whether the gateway has the module for op in version
+//minVersion or later.
+func (c Capabilities) AtLeast(op string, minVersion float64) (bool){
+	version, ok := c[op]
+	return ok && version >= minVersion
+}
+
+//Ops returns the installed modules, sorted.
+func (c Capabilities) Ops() (ops []string){
+	for op := range c{ ops = append(ops, op) }
+	sort.Strings(ops)
+	return ops
+}
+
+//discoveryExempt are the ops that describe the API; they are always allowed.
+var discoveryExempt = map[string]bool{"api_modules" : true, "api_module" : true, "api_version" : true}
+
+//WithDiscovery makes the Host ask the gateway for its modules (api_modules)
+//on first use and refuse, with ErrUnsupported, any op whose module is
+//missing or older than minVersions[op].  Ops not in minVersions only need to
+//be installed.  A gateway that cannot answer api_modules is not gated.
+//
+//Example:
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"),
+//    innGateApi.WithDiscovery(map[string]float64{"auth_login" : 2.0}))
+func WithDiscovery(minVersions map[string]float64) (Option){
+	return func(api *Host){
+		api.gated = true
+		api.minVersions = make(map[string]float64, len(minVersions))
+		for op, version := range minVersions{ api.minVersions[op] = version }
+	}
+}
+
+//discovery holds what api_modules said; it is shared by copies of a Host.
+type discovery struct{
+	mu    sync.Mutex
+	done  bool
+	caps  Capabilities //nil if the gateway could not say
+}
+
+//Capabilities returns the modules the gateway has, asking it with
+//api_modules the first time.  Until a request succeeds every call asks again.
+func (api *Host) Capabilities() (caps Capabilities, err error){
+	ctx := api.ctx
+	if(ctx == nil){ ctx = context.Background() }
+	caps, err = api.discover(ctx)
+	if(err != nil){ return nil, err }
+	if(caps == nil){ return nil, errors.New("innGateApi: gateway did not list its modules") }
+	
+	c := make(Capabilities, len(caps))
+	for op, version := range caps{ c[op] = version }
+	return c, nil
+}
+
+//discover runs api_modules until the gateway answers it.  A gateway that
+//answers with an error yields nil capabilities, which mean "unknown", for
+//good.  A reply that cannot be parsed yields them for this call only; a
+//cancelled context, missing credentials or a transport error is returned.
+//Either way api_modules is asked again next time.
+func (api *Host) discover(ctx context.Context) (caps Capabilities, err error){
+	d := api.discovery
+	if(d == nil){ d = &discovery{} }
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if(d.done){ return d.caps, nil }
+	
+	resp, err := api.WithContext(ctx).Modules()
+	var perr *antlabs.ParseError
+	switch {
+	case errors.As(err, &perr):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case resp.Err() != nil:
+		d.done = true
+		return nil, nil
+	}
+	d.caps, d.done = Capabilities(resp.Modules), true
+	return d.caps, nil
+}
+
+//gate is the middleware installed by WithDiscovery.
+func (api *Host) gate(next Invoker) (Invoker){
+	return func(ctx context.Context, call *Call) (*Reply, error){
+		if(discoveryExempt[call.Op]){ return next(ctx, call) }
+		
+		caps, err := api.discover(ctx)
+		if(err != nil){ return nil, err }
+		if(caps != nil){
+			if(!caps.Has(call.Op)){ return nil, fmt.Errorf("%w: %s is not installed", ErrUnsupported, call.Op) }
+			if(!caps.AtLeast(call.Op, api.minVersions[call.Op])){
+				return nil, fmt.Errorf("%w: %s is version %g, need %g", ErrUnsupported, call.Op, caps[call.Op], api.minVersions[call.Op])
+			}
+		}
+		return next(ctx, call)
+	}
+}
@@ -0,0 +1,77 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+//TestDiscoveryGates refuses ops the fake does not list, or lists in too old
+//a version, without sending them.
+func TestDiscoveryGates(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.SetModule("account_delete", "")
+	metrics := innGateApi.NewMemoryMetrics()
+	ant := gw.Host(innGateApi.WithDiscovery(map[string]float64{"auth_login" : 2.0}), innGateApi.WithMetrics(metrics))
+	
+	_, err := ant.AccountDelete(innGateApi.AccountDeleteRequest{Code : []string{"k2m4p"}})
+	if(!errors.Is(err, innGateApi.ErrUnsupported)){ t.Errorf("account_delete: got %v, want ErrUnsupported", err) }
+	_, err = ant.AuthLogin(innGateApi.AuthLoginRequest{Sid : "0123456789abcdef", Code : "k2m4p"})
+	if(!errors.Is(err, innGateApi.ErrUnsupported)){ t.Errorf("auth_login 1.0: got %v, want ErrUnsupported", err) }
+	if _, err := ant.PlanAll(); err != nil{ t.Errorf("plan_get_all: %v", err) }
+	
+	caps, err := ant.Capabilities()
+	if(err != nil){ t.Fatal(err) }
+	if(caps.Has("account_delete") || !caps.Has("account_add")){ t.Errorf("got capabilities %v", caps.Ops()) }
+	for op := range metrics.Snapshot(){
+		if(op == "account_delete" || op == "auth_login"){ t.Errorf("%s reached the gateway", op) }
+	}
+}
+
+//TestDiscoveryRetries fails the first api_modules, through a credentials
+//error or a cancelled context: discovery must be tried again, not given up.
+func TestDiscoveryRetries(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.SetModule("account_delete", "")
+	
+	var failed atomic.Bool
+	creds := innGateApi.CredentialsFunc(func(ctx context.Context) (string, error){
+		if(failed.CompareAndSwap(false, true)){ return "", innGateApi.ErrNoCredentials }
+		return gw.Password, nil
+	})
+	ant := gw.Host(innGateApi.WithCredentials(creds), innGateApi.WithDiscovery(nil))
+	if _, err := ant.Capabilities(); !errors.Is(err, innGateApi.ErrNoCredentials){ t.Errorf("got %v, want ErrNoCredentials", err) }
+	_, err := ant.AccountDelete(innGateApi.AccountDeleteRequest{Code : []string{"k2m4p"}})
+	if(!errors.Is(err, innGateApi.ErrUnsupported)){ t.Errorf("after a credentials error: got %v, want ErrUnsupported", err) }
+	
+	ant = gw.Host(innGateApi.WithCache(innGateApi.NewCache(nil)), innGateApi.WithDiscovery(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ant.WithContext(ctx).Capabilities(); !errors.Is(err, context.Canceled){ t.Errorf("got %v, want context.Canceled", err) }
+	caps, err := ant.Capabilities()
+	if(err != nil){ t.Fatalf("after a cancelled context: %v", err) }
+	if(caps.Has("account_delete") || !caps.Has("account_add")){ t.Errorf("got capabilities %v", caps.Ops()) }
+}
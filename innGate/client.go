@@ -0,0 +1,82 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"iter"
+)
+
+//Client is the set of InnGate ops.  *Host implements it by talking to a
+//gateway and *Mock implements it for unit tests, so code that depends on an
+//InnGate should take a Client (or one of the smaller interfaces below) rather
+//than a *Host.
+//
+//Example:
+//  func expire(gate innGateApi.Client, userid string) (error){
+//    _, err := innGateApi.ModifyAccount(gate, innGateApi.AccountGetRequest{UserId : userid}, func(account *innGateApi.Account) (error){
+//      account.ValidUntil = time.Now()
+//      return nil
+//    })
+//    return err
+//  }
+type Client interface{
+	ApiClient
+	AuthClient
+	AccountClient
+	PlanClient
+}
+
+//ApiClient covers the ops that describe the gateway's API itself.
+type ApiClient interface{
+	ApiVersion() (*VersionResponse, error)
+	Module(request ModuleRequest) (*ModuleResponse, error)
+	Modules() (*ModulesResponse, error)
+}
+
+//AuthClient covers the ops that authenticate clients and manage their
+//sessions.
+type AuthClient interface{
+	AuthAuthenticate(request AuthAuthenticateRequest) (*AuthAuthenticateResponse, error)
+	AuthInit(request AuthInitRequest) (*AuthInitResponse, error)
+	AuthLogin(request AuthLoginRequest) (*AuthLoginResponse, error)
+	AuthLogout(request AuthLogoutRequest) (*AuthLogoutResponse, error)
+	AuthUpdate(request AuthUpdateRequest) (*AuthUpdateResponse, error)
+	SidGet(request SidGetRequest) (*SidGetResponse, error)
+	PublicIp(request PublicIpRequest) (*PublicIpResponse, error)
+}
+
+//AccountClient covers the ops that manage accounts.
+type AccountClient interface{
+	AccountAdd(request AccountAddRequest) (*AccountAddResponse, error)
+	AccountGet(request AccountGetRequest) (*AccountGetResponse, error)
+	AccountGetAll(arg interface{}) (*AccountGetAllResponse, error)
+	Accounts(arg interface{}) (iter.Seq2[Account, error])
+	AccountUpdate(request AccountUpdateRequest) (*AccountUpdateResponse, error)
+	AccountDelete(request AccountDeleteRequest) (*AccountDeleteResponse, error)
+}
+
+//PlanClient covers the ops that read plans.
+type PlanClient interface{
+	PlanAll() (*PlanAllResponse, error)
+	PlanId(request PlanIdRequest) (*PlanIdResponse, error)
+}
+
+var _ Client = (*Host)(nil)
+var _ Client = (*Mock)(nil)
@@ -23,13 +23,275 @@
 // Example:
 //   import("antlabs/innGate")
 //   func main(){
-//     innGate := innGateApi.Host{Host : "ant.example.com"}
+//     innGate, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"))
+//     if(err != nil){ panic(err) }
 //     resp, _ := innGate.ApiVersion()
 //     fmt.Println("\n\nAPI_Version:", resp.ApiVersion)
 //   }
 package innGateApi
 
+import (
+	"github.com/secesh/gantlabs"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Host is a client for one InnGate.  Create it with New; it cannot be changed
+//afterwards and is safe for concurrent use.
 type Host struct{
-	Host, Pass string
-	Port int
-}
\ No newline at end of file
+	baseURL      string
+	host         string
+	port         int
+	scheme       string
+	path         string
+	endpoint     string //the API address all requests go to, without the querystring
+	creds        Credentials
+	client       *http.Client
+	logger       *slog.Logger
+	mw           []Middleware
+	metrics      Metrics
+	tracer       Tracer
+	ctx          context.Context //set by WithContext
+	gated        bool
+	minVersions  map[string]float64
+	discovery    *discovery
+	quirksOff    map[string]bool
+	quirksAdded  []Quirk
+	versionCache *apiVersionCache
+	planIds      *planIds
+	cache        *Cache
+	location     *time.Location //the gateway's time zone
+	invoke       Invoker //the middleware chain, ending in send
+}
+
+//Option configures a Host; see New.
+type Option func(api *Host)
+
+//WithBaseURL sets the full address of the API, e.g. "http://10.0.0.1/api/" for a
+//lab box or "https://proxy.example.com/inngate/api/" for a gateway behind a
+//reverse proxy.  It takes precedence over WithScheme, WithHost, WithPort and
+//WithPath.
+func WithBaseURL(baseURL string) (Option){ return func(api *Host){ api.baseURL = baseURL } }
+
+//WithHost sets the gateway's hostname or IP; IPv6 literals may be given with or
+//without brackets.  It is required unless WithBaseURL is used.
+func WithHost(host string) (Option){ return func(api *Host){ api.host = host } }
+
+//WithPort sets the port of the API (default: 443 for https, 80 for http).
+func WithPort(port int) (Option){ return func(api *Host){ api.port = port } }
+
+//WithScheme sets the scheme of the API, "https" (the default) or "http".
+func WithScheme(scheme string) (Option){ return func(api *Host){ api.scheme = scheme } }
+
+//WithPath sets the path of the API (default "/api/").
+func WithPath(path string) (Option){ return func(api *Host){ api.path = path } }
+
+//WithHTTPClient sets the client used for requests.  By default requests are
+//sent with a client that accepts the gateway's self-signed certificate.
+func WithHTTPClient(client *http.Client) (Option){ return func(api *Host){ api.client = client } }
+
+//WithLogger logs every request to logger through LoggingMiddleware, ahead of
+//any other middleware.  By default nothing is logged.
+func WithLogger(logger *slog.Logger) (Option){ return func(api *Host){ api.logger = logger } }
+
+//WithMiddleware adds middleware around every op.  The first middleware given
+//sees a call first; it may be used more than once.
+func WithMiddleware(mw ...Middleware) (Option){ return func(api *Host){ api.mw = append(api.mw, mw...) } }
+
+//New returns a Host configured by opts.
+func New(opts ...Option) (api *Host, err error){
+	api = &Host{scheme : "https", path : "/api/"}
+	for _, opt := range opts{ opt(api) }
+	if(api.location == nil){ api.location = time.Local }
+	
+	if(api.baseURL != ""){
+		err = api.splitBaseURL()
+		if(err != nil){ return nil, err }
+	}
+	api.host = strings.TrimSuffix(strings.TrimPrefix(api.host, "["), "]")
+	if(api.host == ""){ return nil, errors.New("innGateApi: no host given (WithHost or WithBaseURL).") }
+	if(api.port < 0 || api.port > 65535){ return nil, errors.New("innGateApi: invalid port " + strconv.Itoa(api.port) + ".") }
+	if(api.scheme != "https" && api.scheme != "http"){ return nil, errors.New("innGateApi: unsupported scheme " + strconv.Quote(api.scheme) + ".") }
+	if(api.creds == nil){ return nil, ErrNoCredentials }
+	creds := api.creds
+	if c, ok := creds.(*CachedCredentials); ok{ creds = c.Source }
+	if(creds == StaticPassword("")){ return nil, fmt.Errorf("%w: the static password given is empty", ErrNoCredentials) }
+	
+	hostPort := api.host
+	if(api.port != 0){
+		hostPort = net.JoinHostPort(api.host, strconv.Itoa(api.port))
+	}else if(strings.Contains(api.host, ":")){
+		hostPort = "[" + api.host + "]"
+	}
+	endpoint := url.URL{Scheme : api.scheme, Host : hostPort, Path : api.path}
+	api.endpoint = endpoint.String()
+	
+	mw := api.mw
+	if(api.logger != nil){ mw = append([]Middleware{LoggingMiddleware(api.logger)}, mw...) }
+	if(api.tracer  != nil){ mw = append([]Middleware{TracingMiddleware(api.tracer, api.host)}, mw...) }
+	if(api.cache != nil){ mw = append(mw[:len(mw):len(mw)], api.cache.Middleware()) }
+	if(api.gated){ mw = append(mw[:len(mw):len(mw)], api.gate) }
+	if(api.metrics != nil){ mw = append(mw[:len(mw):len(mw)], MetricsMiddleware(api.metrics)) }
+	mw = append(mw[:len(mw):len(mw)], api.quirks)
+	api.discovery = &discovery{}
+	api.versionCache = &apiVersionCache{}
+	api.planIds = &planIds{}
+	api.invoke = chain(api.send, mw)
+	return api, nil
+}
+
+//splitBaseURL replaces the scheme, host, port and path with those of baseURL.
+func (api *Host) splitBaseURL() (err error){
+	u, err := url.Parse(api.baseURL)
+	if(err != nil){ return errors.New("innGateApi: invalid base URL: " + err.Error()) }
+	if(u.RawQuery != "" || u.Fragment != "" || u.User != nil){ return errors.New("innGateApi: base URL must not carry a query, fragment or credentials.") }
+	
+	api.scheme, api.host, api.port, api.path = u.Scheme, u.Hostname(), 0, u.Path
+	if(u.Port() != ""){
+		api.port, err = strconv.Atoi(u.Port())
+		if(err != nil){ return errors.New("innGateApi: invalid port in base URL: " + u.Port()) }
+	}
+	if(api.path == ""){ api.path = "/api/" }
+	return nil
+}
+
+//WithContext returns a copy of api whose ops run under ctx: they are
+//cancelled with it, and middleware (e.g. the tracer's spans) sees it.
+//
+//Example:
+//  resp, err := ant.WithContext(r.Context()).AuthLogin(innGateApi.AuthLoginRequest{Sid : sid})
+func (api *Host) WithContext(ctx context.Context) (*Host){
+	c := *api
+	c.ctx = ctx
+	return &c
+}
+
+//request performs op with params through the middleware and returns the
+//fields of the reply.
+func (api *Host) request(op string, params url.Values) (fields []antlabs.Field, err error){
+	return api.do(&Call{Op : op, Params : params})
+}
+
+//stream performs op as request does, but hands the record_N fields of the
+//reply to records as they are read (see Call) rather than returning them.
+func (api *Host) stream(op string, params url.Values, records func(antlabs.Field) (bool)) (fields []antlabs.Field, err error){
+	return api.do(&Call{Op : op, Params : params, stream : records})
+}
+
+func (api *Host) do(call *Call) (fields []antlabs.Field, err error){
+	if(call.Params == nil){ call.Params = url.Values{} }
+	ctx := api.ctx
+	if(ctx == nil){ ctx = context.Background() }
+	invoke := api.invoke
+	if(invoke == nil){ invoke = api.send }
+	reply, err := invoke(ctx, call)
+	if(err != nil){ return nil, err }
+	return reply.Fields, nil
+}
+
+//send is the end of the middleware chain: it adds the api_password and op to
+//the call's parameters and sends it to the gateway.  If the gateway rejects a
+//cached api_password, the cache is invalidated and the call sent once more.
+func (api *Host) send(ctx context.Context, call *Call) (reply *Reply, err error){
+	pass, err := api.password(ctx)
+	if(err != nil){ return nil, err }
+	reply, err = api.sendWith(ctx, call, pass)
+	
+	cache, ok := api.creds.(interface{ Invalidate() })
+	if(err != nil || !ok){ return reply, err }
+	if code, _ := reply.Value("resultcode"); code != "2"{ return reply, err }
+	
+	cache.Invalidate()
+	fresh, err := api.password(ctx)
+	if(err != nil){ return nil, err }
+	if(fresh == pass){ return reply, nil }
+	return api.sendWith(ctx, call, fresh)
+}
+
+//password resolves the api_password.
+func (api *Host) password(ctx context.Context) (pass string, err error){
+	if(api.creds == nil){ return "", ErrNoCredentials }
+	pass, err = api.creds.Password(ctx)
+	if(err == nil && pass == ""){ err = ErrNoCredentials }
+	return pass, err
+}
+
+func (api *Host) sendWith(ctx context.Context, call *Call, pass string) (reply *Reply, err error){
+	call.Attempt++
+	query := url.Values{}
+	for k, v := range call.Params{ query[k] = v }
+	query.Set("api_password", pass)
+	query.Set("op", call.Op)
+	
+	if(call.stream != nil){ return api.sendStream(ctx, call, query) }
+	body, err := antlabs.InnGateApiFetch(ctx, api.client, api.endpoint+"?"+query.Encode())
+	if(err != nil){ return nil, &TransportError{Op : call.Op, Host : api.host, Err : err} }
+	
+	reply = &Reply{Bytes : len(body)}
+	reply.Fields, err = antlabs.ParseApiResponse(string(body))
+	return reply, err
+}
+
+//sendStream sends a call that has a stream, handing it the record_N fields
+//as they are read.  It stops reading, and closes the connection, when the
+//stream wants no more.
+func (api *Host) sendStream(ctx context.Context, call *Call, query url.Values) (reply *Reply, err error){
+	body, err := antlabs.InnGateApiStream(ctx, api.client, api.endpoint+"?"+query.Encode())
+	if(err != nil){ return nil, &TransportError{Op : call.Op, Host : api.host, Err : err} }
+	defer body.Close()
+	
+	counted := &countingReader{r : body}
+	reply = &Reply{}
+	scanner := antlabs.NewFieldScanner(counted)
+	for scanner.Scan(){
+		field := scanner.Field()
+		if(!isRecord(field.Name)){
+			reply.Fields = append(reply.Fields, field)
+			continue
+		}
+		reply.streamed++
+		if(!call.stream(field)){ break }
+	}
+	reply.Bytes = counted.n
+	err = scanner.Err()
+	var perr *antlabs.ParseError
+	if(err != nil && !errors.As(err, &perr)){ err = &TransportError{Op : call.Op, Host : api.host, Err : err} }
+	return reply, err
+}
+
+//countingReader counts the bytes read through it.
+type countingReader struct{
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (n int, err error){
+	n, err = c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+//TransportError reports an op that could not reach the gateway or read its
+//reply, or whose reply had an HTTP status other than 2xx (an
+//*antlabs.StatusError).  It names the op and host but never the URL's
+//querystring, which carries the api_password.
+type TransportError struct{
+	Op   string
+	Host string
+	Err  error
+}
+
+func (e *TransportError) Error() (string){
+	return "innGateApi: " + e.Op + " on " + e.Host + ": " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() (error){ return e.Err }
@@ -0,0 +1,113 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+//TestNewEmptyPassword refuses an empty static password up front, rather than
+//letting the gateway refuse every request.
+func TestNewEmptyPassword(t *testing.T){
+	for _, opt := range []innGateApi.Option{
+		innGateApi.WithPassword(""),
+		innGateApi.WithCredentials(innGateApi.StaticPassword("")),
+		innGateApi.WithCredentials(&innGateApi.CachedCredentials{Source : innGateApi.StaticPassword("")}),
+	}{
+		_, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), opt)
+		if(!errors.Is(err, innGateApi.ErrNoCredentials)){ t.Errorf("got %v, want ErrNoCredentials", err) }
+	}
+}
+
+//recorder is an http.RoundTripper that records the address of each request
+//and answers it as api_version.
+type recorder struct{ urls []string }
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error){
+	u := *req.URL
+	u.RawQuery = ""
+	r.urls = append(r.urls, u.String())
+	body := "op = api_version\napi_version = 3.0\nresult = ok\nresultcode = 0\n"
+	return &http.Response{StatusCode : 200, Body : io.NopCloser(strings.NewReader(body)), Header : http.Header{}, Request : req}, nil
+}
+
+//TestEndpoint checks the address requests go to for each way of naming the
+//gateway.
+func TestEndpoint(t *testing.T){
+	for _, c := range []struct{
+		opts []innGateApi.Option
+		want string
+	}{
+		{[]innGateApi.Option{innGateApi.WithHost("ant.example.com")}, "https://ant.example.com/api/"},
+		{[]innGateApi.Option{innGateApi.WithHost("ant.example.com"), innGateApi.WithPort(8443)}, "https://ant.example.com:8443/api/"},
+		{[]innGateApi.Option{innGateApi.WithHost("10.0.0.1"), innGateApi.WithScheme("http"), innGateApi.WithPath("/inngate/")}, "http://10.0.0.1/inngate/"},
+		{[]innGateApi.Option{innGateApi.WithHost("fe80::1")}, "https://[fe80::1]/api/"},
+		{[]innGateApi.Option{innGateApi.WithHost("[fe80::1]"), innGateApi.WithPort(8443)}, "https://[fe80::1]:8443/api/"},
+		{[]innGateApi.Option{innGateApi.WithHost("fe80::1%eth0")}, "https://[fe80::1%25eth0]/api/"},
+		{[]innGateApi.Option{innGateApi.WithHost("fe80::1%eth0"), innGateApi.WithPort(8443)}, "https://[fe80::1%25eth0]:8443/api/"},
+		{[]innGateApi.Option{innGateApi.WithBaseURL("http://10.0.0.1/api/")}, "http://10.0.0.1/api/"},
+		{[]innGateApi.Option{innGateApi.WithBaseURL("https://ant.example.com:8443")}, "https://ant.example.com:8443/api/"},
+		{[]innGateApi.Option{innGateApi.WithBaseURL("https://[fe80::1%25eth0]:8443/api/")}, "https://[fe80::1%25eth0]:8443/api/"},
+		{[]innGateApi.Option{innGateApi.WithBaseURL("https://proxy.example.com/inngate/api/")}, "https://proxy.example.com/inngate/api/"},
+	}{
+		rec := &recorder{}
+		opts := append(c.opts, innGateApi.WithPassword("secret"), innGateApi.WithHTTPClient(&http.Client{Transport : rec}))
+		ant, err := innGateApi.New(opts...)
+		if(err != nil){ t.Errorf("%s: %v", c.want, err); continue }
+		if _, err := ant.ApiVersion(); err != nil{ t.Errorf("%s: %v", c.want, err); continue }
+		if(len(rec.urls) != 1 || rec.urls[0] != c.want){ t.Errorf("sent to %q, want %s", rec.urls, c.want) }
+	}
+	
+	for _, baseURL := range []string{"https://ant.example.com/api/?op=x", "https://admin:pw@ant.example.com/api/", "https://ant.example.com:http/api/", "ftp://ant.example.com/"}{
+		if _, err := innGateApi.New(innGateApi.WithBaseURL(baseURL), innGateApi.WithPassword("secret")); err == nil{ t.Errorf("%s: no error", baseURL) }
+	}
+}
+
+//TestTransportError fails to reach a gateway, and reaches one with a wrong
+//API path: both are *TransportErrors, and neither shows the api_password.
+func TestTransportError(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.Password = "s3cret-pw"
+	closed := innGateTest.NewServer()
+	closed.Close()
+	
+	for _, c := range []struct{
+		name   string
+		ant    *innGateApi.Host
+		status int
+	}{
+		{"unreachable", closed.Host(innGateApi.WithPassword(gw.Password)), 0},
+		{"wrong path", gw.Host(innGateApi.WithBaseURL(gw.URL + "/wrong/")), 404},
+	}{
+		_, err := c.ant.ApiVersion()
+		var terr *innGateApi.TransportError
+		if(!errors.As(err, &terr) || terr.Op != "api_version"){ t.Errorf("%s: got %T (%v), want a *TransportError", c.name, err, err); continue }
+		if(strings.Contains(err.Error(), gw.Password)){ t.Errorf("%s: the error shows the api_password: %v", c.name, err) }
+		var serr *antlabs.StatusError
+		if(c.status != 0 && (!errors.As(err, &serr) || serr.StatusCode != c.status)){ t.Errorf("%s: got %v, want HTTP status %d", c.name, err, c.status) }
+	}
+}
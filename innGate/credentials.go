@@ -0,0 +1,139 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrNoCredentials is returned when no api_password is available: New was
+//given no credentials, or the Credentials produced an empty password.
+var ErrNoCredentials = errors.New("innGateApi: no api_password available")
+
+//Credentials supplies the api_password.  It is asked before every request, so
+//a password rotated at the source is picked up without a restart; wrap slow
+//sources in CachedCredentials.
+type Credentials interface{
+	Password(ctx context.Context) (string, error)
+}
+
+//CredentialsFunc adapts a function to Credentials.
+type CredentialsFunc func(ctx context.Context) (string, error)
+
+func (f CredentialsFunc) Password(ctx context.Context) (string, error){ return f(ctx) }
+
+//WithCredentials sets where the api_password comes from.  One of it and
+//WithPassword is required; there is no default password.
+func WithCredentials(creds Credentials) (Option){ return func(api *Host){ api.creds = creds } }
+
+//WithPassword sets a fixed api_password; it is WithCredentials(StaticPassword(pass)).
+func WithPassword(pass string) (Option){ return WithCredentials(StaticPassword(pass)) }
+
+//StaticPassword always supplies pass.  New refuses an empty one.
+func StaticPassword(pass string) (Credentials){ return staticPassword(pass) }
+
+type staticPassword string
+
+func (pass staticPassword) Password(ctx context.Context) (string, error){
+	if(pass == ""){ return "", ErrNoCredentials }
+	return string(pass), nil
+}
+
+//EnvPassword supplies the value of the environment variable name.
+func EnvPassword(name string) (Credentials){
+	return CredentialsFunc(func(ctx context.Context) (string, error){
+		pass := os.Getenv(name)
+		if(pass == ""){ return "", fmt.Errorf("%w: environment variable %s is empty", ErrNoCredentials, name) }
+		return pass, nil
+	})
+}
+
+//FilePassword supplies the contents of the file at path, without trailing
+//newlines (e.g. a mounted Kubernetes or Docker secret).
+func FilePassword(path string) (Credentials){
+	return CredentialsFunc(func(ctx context.Context) (string, error){
+		b, err := os.ReadFile(path)
+		if(err != nil){ return "", errors.New("innGateApi: cannot read api_password: " + err.Error()) }
+		pass := strings.TrimRight(string(b), "\r\n")
+		if(pass == ""){ return "", fmt.Errorf("%w: %s is empty", ErrNoCredentials, path) }
+		return pass, nil
+	})
+}
+
+//CommandPassword supplies the first line printed by the command name with
+//args, e.g. CommandPassword("vault", "kv", "get", "-field=password", "secret/inngate").
+//The command's output is never put in an error.
+func CommandPassword(name string, args ...string) (Credentials){
+	return CredentialsFunc(func(ctx context.Context) (string, error){
+		var stdout bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &stdout
+		err := cmd.Run()
+		if(err != nil){ return "", errors.New("innGateApi: api_password command " + name + " failed: " + err.Error()) }
+		pass, _, _ := strings.Cut(stdout.String(), "\n")
+		pass = strings.TrimRight(pass, "\r")
+		if(pass == ""){ return "", fmt.Errorf("%w: command %s printed nothing", ErrNoCredentials, name) }
+		return pass, nil
+	})
+}
+//////////////////////////////////////////////////////////
+
+//CachedCredentials remembers the password from Source for TTL (forever if TTL
+//is 0).  A Host that is told the api_password is wrong (resultcode 2) calls
+//Invalidate and tries once more with a fresh password, so a rotation at the
+//source takes effect before the TTL runs out.
+//
+//Example:
+//  creds := &innGateApi.CachedCredentials{Source : innGateApi.FilePassword("/run/secrets/inngate"), TTL : 5*time.Minute}
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithCredentials(creds))
+type CachedCredentials struct{
+	Source Credentials
+	TTL    time.Duration
+	
+	mu      sync.Mutex
+	pass    string
+	fetched time.Time
+}
+
+func (c *CachedCredentials) Password(ctx context.Context) (string, error){
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if(c.pass != "" && (c.TTL == 0 || time.Since(c.fetched) < c.TTL)){ return c.pass, nil }
+	
+	pass, err := c.Source.Password(ctx)
+	if(err != nil){ return "", err }
+	c.pass, c.fetched = pass, time.Now()
+	return pass, nil
+}
+
+//Invalidate forgets the cached password.
+func (c *CachedCredentials) Invalidate(){
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pass = ""
+}
@@ -0,0 +1,178 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"strconv"
+)
+
+//enum holds the strings the API uses for the values of an enumerated type;
+//the first is the zero value, which is never sent.  Go cannot stop a caller
+//converting any int, e.g. Format(42); Validate rejects such values before a
+//request is sent, and String prints them as Format(42).
+type enum[T ~int] struct{
+	name   string
+	values []string
+}
+
+func (e enum[T]) valid(v T) (bool){ return v >= 0 && int(v) < len(e.values) }
+
+func (e enum[T]) str(v T) (string){
+	if(!e.valid(v)){ return e.name + "(" + strconv.Itoa(int(v)) + ")" }
+	return e.values[v]
+}
+
+func (e enum[T]) parse(s string) (v T, err error){
+	for i, value := range e.values{
+		if(value == s){ return T(i), nil }
+	}
+	return 0, errors.New("innGateApi: invalid " + e.name + " " + strconv.Quote(s) + ".")
+}
+//////////////////////////////////////////////////////////
+
+//Format is the characters the gateway draws a generated userid, password or
+//code from.  The zero value leaves it to the gateway.
+type Format int
+
+const(
+	FormatDefault Format = iota
+	FormatAlpha          //letters only
+	FormatAlnum          //letters and digits
+	FormatNum            //digits only
+)
+
+var formats = enum[Format]{"Format", []string{"", "alpha", "alnum", "num"}}
+
+//ParseFormat parses "alpha", "alnum", "num" or "" (FormatDefault).
+func ParseFormat(s string) (Format, error){ return formats.parse(s) }
+
+func (f Format) String() (string){ return formats.str(f) }
+//////////////////////////////////////////////////////////
+
+//AccountType is what an account is matched by when a user logs in.  The zero
+//value leaves it to the gateway (userid).
+type AccountType int
+
+const(
+	AccountTypeDefault AccountType = iota
+	AccountTypeUserId
+	AccountTypeCode
+)
+
+var accountTypes = enum[AccountType]{"AccountType", []string{"", "userid", "code"}}
+
+//ParseAccountType parses "userid", "code" or "" (AccountTypeDefault).
+func ParseAccountType(s string) (AccountType, error){ return accountTypes.parse(s) }
+
+func (t AccountType) String() (string){ return accountTypes.str(t) }
+//////////////////////////////////////////////////////////
+
+//AuthMode is where auth_authenticate checks credentials.  The zero value
+//leaves it to the gateway (local).
+type AuthMode int
+
+const(
+	AuthModeDefault AuthMode = iota
+	AuthModeLocal            //the gateway's own accounts
+	AuthModeRadius           //the RADIUS server
+)
+
+var authModes = enum[AuthMode]{"AuthMode", []string{"", "local", "radius"}}
+
+//ParseAuthMode parses "local", "radius" or "" (AuthModeDefault).
+func ParseAuthMode(s string) (AuthMode, error){ return authModes.parse(s) }
+
+func (m AuthMode) String() (string){ return authModes.str(m) }
+//////////////////////////////////////////////////////////
+
+//LoginMode is the kind of auth_login.  The zero value leaves it to the
+//gateway (login).
+type LoginMode int
+
+const(
+	LoginModeDefault LoginMode = iota
+	LoginModeLogin
+	LoginModeRelogin         //log a returning user in again from their cookie
+)
+
+var loginModes = enum[LoginMode]{"LoginMode", []string{"", "login", "relogin"}}
+
+//ParseLoginMode parses "login", "relogin" or "" (LoginModeDefault).
+func ParseLoginMode(s string) (LoginMode, error){ return loginModes.parse(s) }
+
+func (m LoginMode) String() (string){ return loginModes.str(m) }
+//////////////////////////////////////////////////////////
+
+//LoginMax is how many times an account may be used to log in: Logins(n) or
+//UnlimitedLogins.  The zero value is unset and sends nothing, which leaves a
+//new account unlimited and an updated one as it was.
+type LoginMax struct{
+	n         int64
+	set       bool
+	unlimited bool
+}
+
+//UnlimitedLogins lets an account log in any number of times.
+var UnlimitedLogins = LoginMax{set : true, unlimited : true}
+
+//Logins allows n logins; it fails if n is less than 1.
+func Logins(n int64) (m LoginMax, err error){
+	if(n < 1){ return LoginMax{}, errors.New("innGateApi: invalid LoginMax " + strconv.FormatInt(n, 10) + ".") }
+	return LoginMax{n : n, set : true}, nil
+}
+
+//MustLogins is Logins for constants; it panics if n is less than 1.
+func MustLogins(n int64) (LoginMax){
+	m, err := Logins(n)
+	if(err != nil){ panic(err) }
+	return m
+}
+
+//ParseLoginMax parses "unlimited", a number of logins, or "" (unset).
+func ParseLoginMax(s string) (m LoginMax, err error){
+	switch s{
+	case "":          return LoginMax{}, nil
+	case "unlimited": return UnlimitedLogins, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if(err != nil || n < 1){ return LoginMax{}, errors.New("innGateApi: invalid LoginMax " + strconv.Quote(s) + ".") }
+	return LoginMax{n : n, set : true}, nil
+}
+
+//IsSet reports whether m is Logins(n) or UnlimitedLogins.
+func (m LoginMax) IsSet() (bool){ return m.set }
+
+//IsUnlimited reports whether m is UnlimitedLogins.
+func (m LoginMax) IsUnlimited() (bool){ return m.unlimited }
+
+//Max returns n for Logins(n); ok is false if m is unset or unlimited.
+func (m LoginMax) Max() (n int64, ok bool){ return m.n, m.set && !m.unlimited }
+
+func (m LoginMax) valid() (bool){ return !m.set || m.unlimited || m.n >= 1 }
+
+//String returns m as the API writes it: "unlimited", the number, or "".
+func (m LoginMax) String() (string){
+	switch {
+	case !m.set:      return ""
+	case m.unlimited: return "unlimited"
+	}
+	return strconv.FormatInt(m.n, 10)
+}
@@ -0,0 +1,138 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"net/url"
+	"time"
+)
+
+//AccountQuery builds an AccountGetAllRequest one filter at a time; the zero
+//value lists every account.  Each method returns a copy, so a query can be
+//extended without changing the original.  AccountGetAll takes a query as well
+//as a request.
+//
+//Example:
+//  guests := innGateApi.AccountQuery{}.Creator("admin").Plan("Guest")
+//  resp, err := ant.AccountGetAll(guests.ValidUntil(time.Now(), time.Now().Add(24*time.Hour)).Enabled(true))
+type AccountQuery struct{
+	request AccountGetAllRequest
+}
+
+//Request returns the request the query has built.
+func (q AccountQuery) Request() (AccountGetAllRequest){ return q.request }
+
+//Creator keeps the accounts made by the admin user name.
+func (q AccountQuery) Creator(name string) (AccountQuery){ q.request.Creator = name; return q }
+
+//Type keeps the accounts of type t.
+func (q AccountQuery) Type(t AccountType) (AccountQuery){ q.request.Type = t; return q }
+
+//Plan keeps the accounts on the plan called name.
+func (q AccountQuery) Plan(name string) (AccountQuery){ q.request.PlanName = name; return q }
+
+//Description keeps the accounts whose description contains s.
+func (q AccountQuery) Description(s string) (AccountQuery){ q.request.Description = s; return q }
+
+//ValidFrom keeps the accounts that start between start and end; a zero time
+//leaves that end of the range open.
+func (q AccountQuery) ValidFrom(start, end time.Time) (AccountQuery){
+	q.request.ValidFromStart, q.request.ValidFromEnd = start, end
+	return q
+}
+
+//ValidUntil keeps the accounts that expire between start and end; a zero time
+//leaves that end of the range open.
+func (q AccountQuery) ValidUntil(start, end time.Time) (AccountQuery){
+	q.request.ValidUntilStart, q.request.ValidUntilEnd = start, end
+	return q
+}
+
+//Created keeps the accounts created between start and end; a zero time leaves
+//that end of the range open.
+func (q AccountQuery) Created(start, end time.Time) (AccountQuery){
+	q.request.CreatedStart, q.request.CreatedEnd = start, end
+	return q
+}
+
+//Enabled keeps the accounts that are enabled, or disabled if !on.  The
+//gateway cannot filter on this; it is checked on the reply.
+func (q AccountQuery) Enabled(on bool) (AccountQuery){ q.request.Enabled = Bool(on); return q }
+
+//LoginCount keeps the accounts logged in at least min and at most max times;
+//a zero bound leaves that end of the range open, as a zero time does.  (Set
+//LoginCountMax to Int(0) on the request for accounts never logged in.)  The
+//gateway cannot filter on this; it is checked on the reply.
+func (q AccountQuery) LoginCount(min, max int64) (AccountQuery){
+	if(min > 0){ q.request.LoginCountMin = Int(min) }
+	if(max > 0){ q.request.LoginCountMax = Int(max) }
+	return q
+}
+
+//UserGroup keeps the accounts whose user group, the Usergroupname the reply
+//lists (Account.UserGroupName), is exactly name.  Plan asks the gateway to
+//match the plan; UserGroup is checked on the reply, as the gateway cannot
+//filter on it.
+func (q AccountQuery) UserGroup(name string) (AccountQuery){ q.request.UserGroupName = name; return q }
+//////////////////////////////////////////////////////////
+
+//accountGetAllRequest returns the request given to AccountGetAll or Accounts
+//as an AccountGetAllRequest or AccountQuery; anything else, e.g. nil, lists
+//every account.
+func accountGetAllRequest(arg interface{}) (request AccountGetAllRequest){
+	switch arg := arg.(type){
+	case AccountGetAllRequest: request = arg
+	case AccountQuery:         request = arg.Request()
+	}
+	return request
+}
+
+//params returns the filters sent to the gateway, with the created range on
+//the gateway's clock, loc.
+func (request AccountGetAllRequest) params(loc *time.Location) (params url.Values){
+	params = url.Values{}
+	if(len(request.Creator)>0){ params.Set("creator", request.Creator)}
+	if(request.Type != AccountTypeDefault){params.Set("type", request.Type.String())}
+	if(!request.ValidFromStart.IsZero()){ params.Set("valid_from_start", formatTime(request.ValidFromStart)) }
+	if(!request.ValidFromEnd.IsZero()){ params.Set("valid_from_end", formatTime(request.ValidFromEnd)) }
+	if(!request.ValidUntilStart.IsZero()){ params.Set("valid_until_start", formatTime(request.ValidUntilStart)) }
+	if(!request.ValidUntilEnd.IsZero()){ params.Set("valid_until_end", formatTime(request.ValidUntilEnd)) }
+	if(len(request.Description)>0){params.Set("description", request.Description)}
+	if(!request.CreatedStart.IsZero()){ params.Set("created_start", request.CreatedStart.In(loc).Format(listLayout)) }
+	if(!request.CreatedEnd.IsZero()){ params.Set("created_end", request.CreatedEnd.In(loc).Format(listLayout)) }
+	if(len(request.PlanName)>0){params.Set("plan_name", request.PlanName)}
+	return params
+}
+
+//filtered reports whether the request has filters the gateway does not apply.
+func (request AccountGetAllRequest) filtered() (bool){
+	return request.Enabled.IsSet() || request.LoginCountMin.IsSet() || request.LoginCountMax.IsSet() || request.UserGroupName != ""
+}
+
+//Match reports whether account passes the filters of the request that are
+//checked on the reply: Enabled, LoginCountMin, LoginCountMax and
+//UserGroupName.
+func (request AccountGetAllRequest) Match(account Account) (bool){
+	if on, ok := request.Enabled.Get(); ok && account.Enable != on{ return false }
+	if n, ok := request.LoginCountMin.Get(); ok && account.LoginCount < n{ return false }
+	if n, ok := request.LoginCountMax.Get(); ok && account.LoginCount > n{ return false }
+	if(request.UserGroupName != "" && account.UserGroupName != request.UserGroupName){ return false }
+	return true
+}
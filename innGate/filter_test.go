@@ -0,0 +1,86 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"net/netip"
+	"testing"
+)
+
+//TestAccountQueryLoginCount lists accounts by login count with one end of
+//the range open, alongside a creator filter the gateway applies.
+func TestAccountQueryLoginCount(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host()
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, Count : 2})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	if _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "bob", Type : innGateApi.AccountTypeCode}); err != nil{ t.Fatal(err) }
+	mac := innGateApi.MustParseMac("00:11:25:87:0B:7D")
+	for i := 0; i < 2; i++{
+		resp, err := ant.AuthLogin(innGateApi.AuthLoginRequest{ClientMac : mac, ClientIp : netip.MustParseAddr("10.0.0.7"), Ppli : "ppli", LocationIndex : 1, Code : add.Codes[0]})
+		if(err != nil){ t.Fatal(err) }
+		if err := resp.Err(); err != nil{ t.Fatal(err) }
+	}
+	
+	admin := innGateApi.AccountQuery{}.Creator("admin")
+	for _, c := range []struct{
+		query innGateApi.AccountQuery
+		want  string
+	}{
+		{admin.LoginCount(1, 0), add.Codes[0]},
+		{admin.LoginCount(0, 1), add.Codes[1]},
+		{admin.LoginCount(2, 2), add.Codes[0]},
+	}{
+		resp, err := ant.AccountGetAll(c.query)
+		if(err != nil){ t.Fatal(err) }
+		if err := resp.Err(); err != nil{ t.Fatal(err) }
+		if(len(resp.Accounts) != 1 || resp.Accounts[0].Code != c.want){ t.Errorf("%+v: got %d accounts, want only %s", c.query.Request(), len(resp.Accounts), c.want) }
+	}
+	if _, ok := admin.LoginCount(1, 0).Request().LoginCountMax.Get(); ok{ t.Errorf("LoginCount(1, 0) set a maximum") }
+}
+
+//TestAccountQueryUserGroup keeps the accounts of one user group, checked on
+//the reply.
+func TestAccountQueryUserGroup(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host()
+	
+	guest, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, PlanName : "Guest"})
+	if(err != nil){ t.Fatal(err) }
+	if err := guest.Err(); err != nil{ t.Fatal(err) }
+	throttled, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, PlanName : "Throttled", Count : 2})
+	if(err != nil){ t.Fatal(err) }
+	if err := throttled.Err(); err != nil{ t.Fatal(err) }
+	
+	for group, want := range map[string]int{"Guest" : 1, "Throttled" : 2, "Throttle" : 0}{
+		resp, err := ant.AccountGetAll(innGateApi.AccountQuery{}.UserGroup(group))
+		if(err != nil){ t.Fatal(err) }
+		if err := resp.Err(); err != nil{ t.Fatal(err) }
+		if(len(resp.Accounts) != want){ t.Errorf("%s: got %d accounts, want %d", group, len(resp.Accounts), want) }
+		for _, account := range resp.Accounts{
+			if(account.UserGroupName != group){ t.Errorf("%s: listed %s of %s", group, account.Code, account.UserGroupName) }
+		}
+	}
+}
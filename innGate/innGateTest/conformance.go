@@ -0,0 +1,460 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateTest
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Suite describes the test data used by the conformance suite.  The zero
+//value is usable; empty fields take the defaults noted below.
+type Suite struct{
+	Creator       string           //creator of the test account (default: admin)
+	Description   string           //description of the test account (default: gantlabs-conformance)
+	Lifetime      time.Duration    //valid_until of the test account, from now (default: 1 hour)
+	ClientMac     net.HardwareAddr //test device (default: 02:00:00:00:00:01, a locally administered address)
+	ClientIp      netip.Addr       //(default: 10.0.0.1)
+	LocationIndex string           //(default: 1)
+	Ppli          string           //(default: eth0.100)
+}
+
+//Report records what the suite found, op by op.
+type Report struct{
+	Ops []OpReport
+}
+
+//OpReport is the outcome of exercising one op.  Skipped is set when the op
+//could not be exercised because an earlier op it depends on failed.
+type OpReport struct{
+	Op       string
+	Err      error
+	Skipped  bool
+	Optional bool //failures are logged but do not fail the test
+	Fields   []FieldReport
+}
+
+//FieldReport is the outcome of checking one field of a reply.
+type FieldReport struct{
+	Name   string
+	Ok     bool
+	Detail string
+}
+
+//Ok reports whether the op and every field checked behaved as expected.
+func (op *OpReport) Ok() (bool){
+	if(op.Err != nil || op.Skipped){ return false }
+	for _, f := range op.Fields{
+		if(!f.Ok){ return false }
+	}
+	return true
+}
+
+//Ok reports whether every op that is not optional behaved as expected.
+func (r *Report) Ok() (bool){
+	for i := range r.Ops{
+		if(!r.Ops[i].Optional && !r.Ops[i].Ok()){ return false }
+	}
+	return true
+}
+
+//String renders the report as one line per op, followed by the fields
+//that did not behave as expected.
+func (r *Report) String() (string){
+	var b strings.Builder
+	for _, op := range r.Ops{
+		status := "ok  "
+		switch {
+		case op.Skipped:
+			status = "skip"
+		case !op.Ok() && op.Optional:
+			status = "warn"
+		case !op.Ok():
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %s", status, op.Op)
+		if(op.Err != nil){ fmt.Fprintf(&b, ": %v", op.Err) }
+		b.WriteString("\n")
+		for _, f := range op.Fields{
+			if(!f.Ok){ fmt.Fprintf(&b, "       %s: %s\n", f.Name, f.Detail) }
+		}
+	}
+	return b.String()
+}
+
+//Conformance runs the default Suite against ant.  See Suite.Run.
+func Conformance(t *testing.T, ant innGateApi.Client) (*Report){
+	return (&Suite{}).Run(t, ant)
+}
+
+//Run exercises every op implemented by innGateApi against ant (usually a
+//*innGateApi.Host, or one wrapped by your own code), one subtest
+//per op, and reports which ops and fields behave as the library expects.
+//
+//The suite is not read-only: it creates one account (deleted again at the
+//end, and set to expire after Lifetime in case it is not), and logs the
+//test device in and out.  Point it at the fake from NewServer, or at a lab
+//appliance; not at a gateway that is serving guests.
+func (s *Suite) Run(t *testing.T, ant innGateApi.Client) (report *Report){
+	s.defaults()
+	c := &conformance{suite : s, ant : ant, report : &Report{}}
+	t.Cleanup(func(){
+		if(c.code != ""){ ant.AccountDelete(innGateApi.AccountDeleteRequest{Code : c.code}) }
+		t.Logf("InnGate conformance report:\n%s", c.report)
+	})
+
+	c.run(t, "api_version", false, c.apiVersion)
+	c.run(t, "api_modules", false, c.apiModules)
+	c.run(t, "api_module", false, c.apiModule)
+	c.run(t, "plan_get_all", false, c.planAll)
+	c.run(t, "plan_get_id", false, c.planId)
+	c.run(t, "account_add", false, c.accountAdd)
+	c.run(t, "account_get", false, c.accountGet)
+	c.run(t, "account_update", false, c.accountUpdate)
+	c.run(t, "account_get_all", false, c.accountGetAll)
+	c.run(t, "auth_authenticate", false, c.authAuthenticate)
+	c.run(t, "auth_init", false, c.authInit)
+	c.run(t, "sid_get", false, c.sidGet)
+	c.run(t, "auth_login", false, c.authLogin)
+	c.run(t, "auth_update", false, c.authUpdate)
+	c.run(t, "publicip_get", true, c.publicIp) //plans without public IPs refuse this.
+	c.run(t, "auth_logout", false, c.authLogout)
+	c.run(t, "account_delete", false, c.accountDelete)
+	return c.report
+}
+
+func (s *Suite) defaults(){
+	if(s.Creator       == ""){ s.Creator = "admin" }
+	if(s.Description   == ""){ s.Description = "gantlabs-conformance" }
+	if(s.Lifetime      == 0 ){ s.Lifetime = time.Hour }
+	if(s.ClientMac     == nil){ s.ClientMac = innGateApi.MustParseMac("02:00:00:00:00:01") }
+	if(!s.ClientIp.IsValid()){ s.ClientIp = netip.MustParseAddr("10.0.0.1") }
+	if(s.LocationIndex == ""){ s.LocationIndex = "1" }
+	if(s.Ppli          == ""){ s.Ppli = "eth0.100" }
+}
+
+//conformance carries state from one op to the next.
+type conformance struct{
+	suite  *Suite
+	ant    innGateApi.Client
+	report *Report
+
+	plans      []innGateApi.Plan
+	code       string
+	userid     string
+	password   string
+	validUntil time.Time
+	createTime time.Time //from account_get
+	sid        string
+	loggedIn   bool
+}
+
+//check is handed to each op; it records field results on the op's report
+//and fails the subtest when a required op misbehaves.
+type check struct{
+	t  *testing.T
+	op *OpReport
+}
+
+func (c *check) field(name string, ok bool, format string, args ...interface{}){
+	c.op.Fields = append(c.op.Fields, FieldReport{Name : name, Ok : ok, Detail : fmt.Sprintf(format, args...)})
+	if(!ok && !c.op.Optional){ c.t.Errorf("%s: %s", name, fmt.Sprintf(format, args...)) }
+}
+
+func (c *check) equal(name, got, want string){
+	c.field(name, got == want, "got %q, want %q", got, want)
+}
+
+//common checks the fields every reply carries.
+func (c *check) common(op, result string, resultcode int64, moduleVersion float64, checkVersion bool){
+	c.equal("op", op, c.op.Op)
+	c.equal("result", result, "ok")
+	c.field("resultcode", resultcode == 0, "got %d, want 0", resultcode)
+	if(checkVersion){ c.field("version", moduleVersion > 0, "got %v, want a module version", moduleVersion) }
+}
+
+//skip marks the op as not exercised because of an earlier failure.
+func (c *check) skip(reason string){
+	c.op.Skipped = true
+	c.t.Skip(reason)
+}
+
+func (c *conformance) run(t *testing.T, op string, optional bool, fn func(*check) error){
+	c.report.Ops = append(c.report.Ops, OpReport{Op : op, Optional : optional})
+	rep := &c.report.Ops[len(c.report.Ops)-1]
+	t.Run(op, func(t *testing.T){
+		if err := fn(&check{t : t, op : rep}); err != nil{
+			rep.Err = err
+			if(optional){
+				t.Logf("optional op failed: %v", err)
+			}else{
+				t.Error(err)
+			}
+		}
+	})
+}
+
+func (c *conformance) apiVersion(k *check) (error){
+	resp, err := c.ant.ApiVersion()
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, 0, false)
+	k.field("api_version", resp.ApiVersion > 0, "got %v, want a version", resp.ApiVersion)
+	return nil
+}
+
+func (c *conformance) apiModules(k *check) (error){
+	resp, err := c.ant.Modules()
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("count", resp.Count == int64(len(resp.Modules)), "count is %d but %d modules were listed", resp.Count, len(resp.Modules))
+	for _, op := range []string{"account_add", "account_get", "auth_login", "plan_get_all"}{
+		_, ok := resp.Modules[op]
+		k.field("modules", ok, "%s is not listed", op)
+	}
+	return nil
+}
+
+func (c *conformance) apiModule(k *check) (error){
+	resp, err := c.ant.Module(innGateApi.ModuleRequest{Module : "api_modules"})
+	if(err != nil){ return err }
+	//api_module reports the version of the module asked about, not its own.
+	k.common(resp.Op, resp.Result, resp.Resultcode, 0, false)
+	k.field("version", resp.Version > 0, "got %v, want the version of api_modules", resp.Version)
+	return nil
+}
+
+func (c *conformance) planAll(k *check) (error){
+	resp, err := c.ant.PlanAll()
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("records", len(resp.Plans) > 0, "no plans returned")
+	for _, p := range resp.Plans{
+		k.field("record.id", p.Id > 0, "plan %q has id %d", p.Name, p.Id)
+		k.field("record.name", p.Name != "", "plan %d has no name", p.Id)
+	}
+	c.plans = resp.Plans
+	return nil
+}
+
+func (c *conformance) planId(k *check) (error){
+	if(len(c.plans) == 0){ k.skip("no plans to look up") }
+	want := c.plans[0]
+	resp, err := c.ant.PlanId(innGateApi.PlanIdRequest{Name : want.Name})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("plan_id", resp.Id == want.Id, "got %d for plan %q, want %d", resp.Id, want.Name, want.Id)
+	return nil
+}
+
+func (c *conformance) accountAdd(k *check) (error){
+	req := innGateApi.AccountAddRequest{
+		Creator     : c.suite.Creator,
+		Description : c.suite.Description,
+		ValidFrom   : innGateApi.Set(time.Now()),
+		ValidUntil  : innGateApi.Set(time.Now().Add(c.suite.Lifetime).Truncate(time.Second)),
+		SharingMax  : 1,
+	}
+	if(len(c.plans) > 0){ req.PlanName = c.plans[0].Name }
+	resp, err := c.ant.AccountAdd(req)
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("created", resp.Created == 1, "got %d, want 1", resp.Created)
+	k.field("codes", len(resp.Codes) == 1 && resp.Codes[0] != "", "got %q, want one code", resp.Codes)
+	k.field("userids", len(resp.UserIds) == 1 && resp.UserIds[0] != "", "got %q, want one userid", resp.UserIds)
+	k.field("passwords", len(resp.Passwords) == 1, "got %d passwords, want 1", len(resp.Passwords))
+	if(len(resp.Codes) == 1 && len(resp.UserIds) == 1 && len(resp.Passwords) == 1){
+		c.code, c.userid, c.password = resp.Codes[0], resp.UserIds[0], resp.Passwords[0]
+		c.validUntil, _ = req.ValidUntil.Get()
+	}
+	return nil
+}
+
+func (c *conformance) accountGet(k *check) (error){
+	if(c.code == ""){ k.skip("account_add did not create an account") }
+	resp, err := c.ant.AccountGet(innGateApi.AccountGetRequest{Code : c.code})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	if(len(resp.Accounts) != 1){
+		k.field("accounts", false, "got %d accounts, want 1", len(resp.Accounts))
+		return nil
+	}
+	account := resp.Accounts[0]
+	k.equal("code", account.Code, c.code)
+	k.equal("userid", account.UserId, c.userid)
+	k.equal("description", account.Description, c.suite.Description)
+	k.field("enabled", account.Enable, "got false, want a new account to be enabled")
+	k.field("valid_until", account.ValidUntil.Equal(c.validUntil), "got %v, want %v", account.ValidUntil, c.validUntil)
+	k.field("create_time", !account.CreateTime.IsZero(), "got no create_time")
+	c.createTime = account.CreateTime
+	k.field("sharing_max", account.SharingMax == 1, "got %d, want 1", account.SharingMax)
+	k.field("sharing_index", len(account.Sharing) > 0 && account.Sharing[0].Index == 0, "got %v, want the first device at index 0", account.Sharing)
+	if(len(c.plans) > 0){ k.equal("plan", account.UserGroupName, c.plans[0].Name) }
+	return nil
+}
+
+func (c *conformance) accountUpdate(k *check) (error){
+	if(c.code == ""){ k.skip("account_add did not create an account") }
+	description := c.suite.Description + "-updated"
+	resp, err := c.ant.AccountUpdate(innGateApi.AccountUpdateRequest{Code : c.code, Description : innGateApi.Set(description)})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+
+	got, err := c.ant.AccountGet(innGateApi.AccountGetRequest{Code : c.code})
+	if(err != nil){ return err }
+	k.field("description", len(got.Accounts) > 0 && got.Accounts[0].Description == description,
+		"account_get shows %+v after the update, want description %q", got.Accounts, description)
+	return nil
+}
+
+func (c *conformance) accountGetAll(k *check) (error){
+	if(c.code == ""){ k.skip("account_add did not create an account") }
+	resp, err := c.ant.AccountGetAll(innGateApi.AccountGetAllRequest{Creator : c.suite.Creator})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("count", resp.Count == int64(len(resp.Accounts)), "count is %d but %d records were decoded", resp.Count, len(resp.Accounts))
+	k.field("header", len(resp.Header) == 17, "got %d columns, want 17", len(resp.Header))
+	var found *innGateApi.Account
+	for i := range resp.Accounts{
+		if(resp.Accounts[i].Code == c.code){ found = &resp.Accounts[i] }
+	}
+	k.field("record", found != nil, "account %s is not listed", c.code)
+	if(found != nil){
+		k.equal("record.userid", found.UserId, c.userid)
+		k.equal("record.creator", found.Creator, c.suite.Creator)
+		k.field("record.enable", found.Enable, "got false, want a new account to be enabled")
+		k.field("record.validuntil", found.ValidUntil.Equal(c.validUntil), "got %v, want %v", found.ValidUntil, c.validUntil)
+		//account_get_all's create time has no zone: a mismatch means the Host
+		//needs WithLocation.
+		k.field("record.createtime", c.createTime.IsZero() || found.CreateTime.Equal(c.createTime),
+			"got %v, want %v as account_get gave it", found.CreateTime, c.createTime)
+	}
+	
+	//The same account through the gateway's date filters and the client-side ones.
+	query := innGateApi.AccountQuery{}.Creator(c.suite.Creator).Enabled(true).
+		ValidUntil(c.validUntil.Add(-time.Minute), c.validUntil.Add(time.Minute))
+	if(!c.createTime.IsZero()){ query = query.Created(c.createTime.Add(-time.Minute), c.createTime.Add(time.Minute)) }
+	filtered, err := c.ant.AccountGetAll(query)
+	if(err != nil){ return err }
+	listed := false
+	for _, account := range filtered.Accounts{
+		if(account.Code == c.code){ listed = true }
+	}
+	k.field("filters", listed, "account %s is not listed when filtered by its creator, expiry and create time", c.code)
+	
+	//The same account through the streaming listing.
+	streamed := false
+	for account, err := range c.ant.Accounts(innGateApi.AccountQuery{}.Creator(c.suite.Creator)){
+		if(err != nil){ return err }
+		if(account.Code == c.code){ streamed = true; break }
+	}
+	k.field("stream", streamed, "account %s is not listed by Accounts", c.code)
+	return nil
+}
+
+func (c *conformance) authAuthenticate(k *check) (error){
+	if(c.code == ""){ k.skip("account_add did not create an account") }
+	resp, err := c.ant.AuthAuthenticate(innGateApi.AuthAuthenticateRequest{Code : c.code})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	return nil
+}
+
+func (c *conformance) authInit(k *check) (error){
+	resp, err := c.ant.AuthInit(innGateApi.AuthInitRequest{
+		ClientMac     : c.suite.ClientMac,
+		ClientIp      : c.suite.ClientIp,
+		LocationIndex : c.suite.LocationIndex,
+		Ppli          : c.suite.Ppli,
+		NewSid        : 1,
+	})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("sid", len(resp.Sid) == 32, "got %q, want a 32-character session ID", resp.Sid)
+	k.equal("client_mac", resp.ClientMac.String(), c.suite.ClientMac.String())
+	k.equal("client_ip", resp.ClientIp.String(), c.suite.ClientIp.String())
+	k.equal("ppli", resp.Ppli, c.suite.Ppli)
+	c.sid = resp.Sid
+	return nil
+}
+
+func (c *conformance) sidGet(k *check) (error){
+	if(c.sid == ""){ k.skip("auth_init did not return a session ID") }
+	resp, err := c.ant.SidGet(innGateApi.SidGetRequest{Sid : c.sid})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.equal("sid", resp.Sid, c.sid)
+	k.equal("client_mac", resp.ClientMac.String(), c.suite.ClientMac.String())
+	k.equal("client_ip", resp.ClientIp.String(), c.suite.ClientIp.String())
+	k.equal("location_index", resp.LocationIndex, c.suite.LocationIndex)
+	k.equal("ppli", resp.Ppli, c.suite.Ppli)
+	return nil
+}
+
+func (c *conformance) authLogin(k *check) (error){
+	if(c.sid == "" || c.code == ""){ k.skip("no session ID or no account to log in with") }
+	resp, err := c.ant.AuthLogin(innGateApi.AuthLoginRequest{Sid : c.sid, Code : c.code})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.equal("sid", resp.Sid, c.sid)
+	k.equal("client_mac", resp.ClientMac.String(), c.suite.ClientMac.String())
+	c.loggedIn = resp.Result == "ok"
+	return nil
+}
+
+func (c *conformance) authUpdate(k *check) (error){
+	if(!c.loggedIn){ k.skip("the test device is not logged in") }
+	resp, err := c.ant.AuthUpdate(innGateApi.AuthUpdateRequest{ClientMac : c.suite.ClientMac, Duration : innGateApi.Set(30*time.Minute)})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	return nil
+}
+
+func (c *conformance) publicIp(k *check) (error){
+	if(!c.loggedIn){ k.skip("the test device is not logged in") }
+	resp, err := c.ant.PublicIp(innGateApi.PublicIpRequest{Sid : c.sid})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("public_ip", resp.PublicIp.IsValid(), "no address returned")
+	return nil
+}
+
+func (c *conformance) authLogout(k *check) (error){
+	if(!c.loggedIn){ k.skip("the test device is not logged in") }
+	resp, err := c.ant.AuthLogout(innGateApi.AuthLogoutRequest{Sid : c.sid})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.equal("sid", resp.Sid, c.sid)
+	k.field("accounting", resp.Accounting != "", "no accounting result")
+	return nil
+}
+
+func (c *conformance) accountDelete(k *check) (error){
+	if(c.code == ""){ k.skip("account_add did not create an account") }
+	resp, err := c.ant.AccountDelete(innGateApi.AccountDeleteRequest{Code : c.code})
+	if(err != nil){ return err }
+	k.common(resp.Op, resp.Result, resp.Resultcode, resp.ModuleVersion, true)
+	k.field("deleted", resp.Deleted == 1, "got %d, want 1", resp.Deleted)
+	if(resp.Deleted == 1){ c.code = "" }
+	return nil
+}
@@ -0,0 +1,49 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateTest_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"testing"
+)
+
+//TestConformance keeps the fake and the conformance suite in step: every op,
+//including the optional ones, must pass against NewServer.
+func TestConformance(t *testing.T){
+	gw := innGateTest.NewServer()
+	t.Cleanup(gw.Close) //after the suite's own cleanup, which deletes its account
+	report := innGateTest.Conformance(t, gw.Host())
+	if(!report.Ok()){ t.Errorf("the fake fails its own suite:\n%s", report) }
+	for _, op := range report.Ops{
+		if(op.Skipped || op.Err != nil){ t.Errorf("%s: skipped or failed (%v)", op.Op, op.Err) }
+	}
+}
+
+//TestRemovedModule removes a module from the fake, which must then answer
+//its op as the gateway does, with resultcode 3.
+func TestRemovedModule(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.SetModule("account_delete", "")
+	resp, err := gw.Host().AccountDelete(innGateApi.AccountDeleteRequest{Code : []string{"k2m4p"}})
+	if(err != nil){ t.Fatal(err) }
+	if(resp.Resultcode != 3){ t.Errorf("got resultcode %d (%v), want 3", resp.Resultcode, resp.Err()) }
+}
@@ -0,0 +1,141 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateTest_test
+
+import (
+	"github.com/secesh/gantlabs"
+	"github.com/secesh/gantlabs/innGate"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+//These are fuzz targets for the reply parser and every decoder in
+//innGateApi.  They check that no input panics and that every failure is
+//reported as an *antlabs.ParseError.  go test runs each on its seeds and on
+//the corpus in testdata/fuzz; go test -fuzz=FuzzDecode fuzzes one.
+
+//ops lists the ops innGateApi.Decode understands, for FuzzDecode.
+var ops = []string{"api_module", "api_modules", "api_version", "auth_authenticate", "auth_login",
+	"auth_logout", "auth_init", "auth_update", "sid_get", "publicip_get", "account_add",
+	"account_get", "account_get_all", "account_delete", "account_update", "plan_get_all", "plan_get_id"}
+
+//seedReplies are replies of the shape the gateway sends, used as the
+//starting corpus.
+var seedReplies = []string{
+	"op = api_version\napi_version = 3.0\nresult = ok\nresultcode = 0\n",
+	"op = api_module\nversion = 1.01\nresult = ok\nresultcode = 0\n",
+	"op = api_modules\nversion = 1.0\nresult = ok\nresultcode = 0\ncount = 2\nmodules = account_add 1.0|auth_login 2.0\n",
+	"op = auth_authenticate\nversion = 1.0\nresult = ok\nresultcode = 0\nradiusattrs = Antlabs-User-Group-Name=stored_volume|Session-Timeout=86400\n",
+	"op = auth_init\nversion = 1.0\nresult = ok\nresultcode = 0\nsid = 86cb1a5deb036467a9c2bc36e13971ef\nclient_mac = 00:11:25:87:0B:7D\nclient_ip = 10.10.1.244\nppli = eth0.210\nvlan = \n",
+	"op = sid_get\nversion = 1.0\nresult = ok\nresultcode = 0\nsid = 86cb1a5deb036467a9c2bc36e13971ef\nclient_mac = 00:11:25:87:0B:7D\nppli = eth0.210\nvlan = \nclient_ip = 10.10.1.244\nlocation_index = 6\nlogin-userid = abc\n",
+	"op = account_add\nversion = 1.0\nresult = ok\nresultcode = 0\ncreated = 2\nuserids = abcde|fghij\npasswords = x1y2z|a3b4c\ncodes = k2m4p|q8r7s\n",
+	"op = account_get\nversion = 1.0\nresult = ok\nresultcode = 0\nuserid = abcde|abcde\ncode = k2m4p|k2m4p\nsharing_index = 0|1\nclient_mac = 00:11:25:87:0B:7D|\ndescription = lobby|lobby\nenabled = yes|yes\n" +
+		"valid_from = Thu, 25 Jun 2009 14:59:00 +0800|Thu, 25 Jun 2009 14:59:00 +0800\nvalid_until = Fri, 26 Jun 2009 14:59:00 +0800|Fri, 26 Jun 2009 14:59:00 +0800\n" +
+		"login_limit = off|off\nlogin_max = 0|0\nlogin_count = 1|0\nsharing_max = 2|2\nplan = Guest|Guest\nduration_balance = unlimited|unlimited\nvolume_balance = unlimited|unlimited\n" +
+		"create_time = Thu, 25 Jun 2009 14:59:00 +0800|Thu, 25 Jun 2009 14:59:00 +0800\nupdate_time = Thu, 25 Jun 2009 14:59:00 +0800|Thu, 25 Jun 2009 14:59:00 +0800\n",
+	"op = account_get_all\nversion = 1.0\nresult = ok\nresultcode = 0\ncount = 1\nheader = Type|Creator|Userid|Code|Description|Enable|Validfrom|Validuntil|Loginlimit|Loginmax|Logincount|Sharingmax|Usergroupname|Createtime|Updatetime|Accounting|billingID\n" +
+		"record_1 = userid|admin|abcde|k2m4p|lobby|yes|1245912740|1245999140|off|0|1|1|Guest|2009-06-25 14:52:20|2009-06-25 14:52:20||\n",
+	"op = account_get_all\nversion = 1.0\nresult = error\nresultcode = 90\nerror = An invalid value was provided for an input argument\n",
+	"op = plan_get_all\nversion = 1.0\nresult = ok\nresultcode = 0\nrecord_1 = 4|0.00|unlimited|off|0|off|0|logout|on|256|kbps|on|128|kbps|off|off|off|Throttled\n",
+	"op = plan_get_id\nversion = 1.0\nresult = ok\nresultcode = 0\nplan_id = 4\n",
+	"op = account_delete\nversion = 1.0\nresult = ok\nresultcode = 0\ndeleted = 1\n",
+	"<html><body>404 Not Found</body></html>",
+}
+
+//wantParseError fails t unless err is an *antlabs.ParseError.
+func wantParseError(t *testing.T, err error){
+	var perr *antlabs.ParseError
+	if(!errors.As(err, &perr)){ t.Errorf("got %T (%v), want *antlabs.ParseError", err, err) }
+}
+
+//FuzzParseApiResponse fuzzes antlabs.ParseApiResponse.  Besides not
+//panicking, every field returned must have a name and a line number that
+//exists in the body.
+func FuzzParseApiResponse(f *testing.F){
+	for _, seed := range seedReplies{ f.Add(seed) }
+	f.Fuzz(func(t *testing.T, body string){
+		fields, err := antlabs.ParseApiResponse(body)
+		if(err != nil){ wantParseError(t, err); return }
+		lines := strings.Count(body, "\n") + 1
+		for _, field := range fields{
+			if(field.Name == "" || field.Line < 1 || field.Line > lines){ t.Errorf("bad field %+v from a %d line body", field, lines) }
+		}
+	})
+}
+
+//FuzzFieldScanner fuzzes antlabs.FieldScanner, which must read the same
+//fields as ParseApiResponse and fail on the same bodies.
+func FuzzFieldScanner(f *testing.F){
+	for _, seed := range seedReplies{ f.Add(seed) }
+	f.Fuzz(func(t *testing.T, body string){
+		want, wantErr := antlabs.ParseApiResponse(body)
+		var got []antlabs.Field
+		scanner := antlabs.NewFieldScanner(strings.NewReader(body))
+		for scanner.Scan(){ got = append(got, scanner.Field()) }
+		err := scanner.Err()
+		if(err != nil){ wantParseError(t, err) }
+		if((err == nil) != (wantErr == nil)){ t.Fatalf("got error %v, ParseApiResponse gave %v", err, wantErr) }
+		if(err != nil){ return }
+		if(!slices.Equal(got, want)){ t.Errorf("got %+v, ParseApiResponse gave %+v", got, want) }
+	})
+}
+
+//FuzzDecode fuzzes innGateApi.Decode, and so the reply decoder of every op.
+func FuzzDecode(f *testing.F){
+	for _, seed := range seedReplies{
+		for i := range ops{ f.Add(uint8(i), seed) }
+	}
+	f.Fuzz(func(t *testing.T, op uint8, body string){
+		_, err := innGateApi.Decode(ops[int(op)%len(ops)], body)
+		if(err != nil){ wantParseError(t, err) }
+	})
+}
+
+//FuzzDecodeAccountRecord fuzzes innGateApi.DecodeAccountRecord.
+func FuzzDecodeAccountRecord(f *testing.F){
+	f.Add("userid|admin|abcde|k2m4p|lobby|yes|1245912740|1245999140|off|0|1|1|Guest|2009-06-25 14:52:20|2009-06-25 14:52:20||")
+	f.Add("code|pms|||||0|0|on|5|5|2|||||")
+	f.Fuzz(func(t *testing.T, value string){
+		_, err := innGateApi.DecodeAccountRecord(antlabs.Field{Line : 1, Name : "record_1", Value : value})
+		if(err != nil){ wantParseError(t, err) }
+	})
+}
+
+//FuzzDecodePlanRecord fuzzes innGateApi.DecodePlanRecord.
+func FuzzDecodePlanRecord(f *testing.F){
+	f.Add("4|0.00|unlimited|off|0|off|0|logout|on|256|kbps|on|128|kbps|off|off|off|Throttled")
+	f.Add("1|5.00|fixed_duration|on|60|on|500|change|off|0|mbps|off|0|mbps|ask|on|on|Hour")
+	f.Fuzz(func(t *testing.T, value string){
+		_, err := innGateApi.DecodePlanRecord(antlabs.Field{Line : 1, Name : "record_1", Value : value})
+		if(err != nil){ wantParseError(t, err) }
+	})
+}
+
+//FuzzDecodeModules fuzzes innGateApi.DecodeModules.
+func FuzzDecodeModules(f *testing.F){
+	f.Add("account_add 1.0|auth_login 2.0")
+	f.Add("")
+	f.Add("api_version")
+	f.Fuzz(func(t *testing.T, value string){
+		_, err := innGateApi.DecodeModules(antlabs.Field{Line : 1, Name : "modules", Value : value})
+		if(err != nil){ wantParseError(t, err) }
+	})
+}
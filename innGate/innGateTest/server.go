@@ -0,0 +1,711 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package innGateTest provides utilities for testing code that talks to an
+// ANTLabs InnGate: an in-memory fake gateway that speaks the HTTP API, and a
+// conformance suite that can be pointed at the fake or at a real appliance.
+//
+// Example:
+//   func TestGateway(t *testing.T){
+//     gw := innGateTest.NewServer()
+//     defer gw.Close()
+//     innGateTest.Conformance(t, gw.Host())
+//   }
+package innGateTest
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Server is a fake InnGate.  It keeps accounts, plans and sessions in memory
+//and answers the HTTP API the way the appliance does (key = value lines).
+//It is not a complete emulation; it implements the modules this library
+//supports, which is enough to exercise them end-to-end.
+type Server struct{
+	*httptest.Server
+	Password   string //the api_password the fake expects (default: admin)
+	ApiVersion string
+
+	mu       sync.Mutex
+	modules  map[string]string
+	plans    []fakePlan
+	accounts []*fakeAccount
+	sessions map[string]*fakeSession
+	handlers map[string]http.HandlerFunc //see HandleOp
+	serial   int64
+	rand     *rand.Rand
+}
+
+type fakePlan struct{
+	id     int64
+	name   string
+	record string //fields 2-17 of the plan_get_all record (everything but id and name)
+}
+
+type fakeAccount struct{
+	accountType, creator, userid, code, password string
+	description, plan, billingId, accounting      string
+	enabled, loginLimit                          bool
+	validFrom, validUntil                        int64 //unix; 0 means unset
+	loginMax, loginCount, sharingMax             int64 //loginMax 0 means unlimited
+	allowedLoginZone                             int64
+	createTime, updateTime                       time.Time
+	devices                                      []string //client MACs, by sharing index
+}
+
+type fakeSession struct{
+	sid, clientMac, clientIp, locationIndex, ppli, vlan string
+	extra    map[string]string
+	loggedIn bool
+	code     string //account used to log in
+}
+
+//NewServer starts a fake InnGate listening on a local TLS port.  The
+//caller should Close it when finished.
+func NewServer() (s *Server){
+	s = &Server{
+		Password   : "admin",
+		ApiVersion : "3.0",
+		modules    : map[string]string{},
+		sessions   : map[string]*fakeSession{},
+		rand       : rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, op := range []string{"account_add", "account_delete", "account_get", "account_get_all",
+		"account_update", "api_module", "api_modules", "api_version", "auth_authenticate",
+		"auth_init", "auth_login", "auth_logout", "auth_update", "sid_get", "publicip_get",
+		"plan_get_all", "plan_get_id"}{
+		s.modules[op] = "1.0"
+	}
+	s.plans = []fakePlan{
+		{id : 1, name : "Guest",     record : "0.00|unlimited|off|0|off|0|logout|off|0|kbps|off|0|kbps|off|off|off"},
+		{id : 4, name : "Throttled", record : "0.00|unlimited|off|0|off|0|logout|on|256|kbps|on|128|kbps|off|off|off"},
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveApi))
+	return s
+}
+
+//Host returns an innGateApi.Host configured to talk to the fake.  opts are
+//applied after the fake's own, e.g. to add a logger.
+func (s *Server) Host(opts ...innGateApi.Option) (ant *innGateApi.Host){
+	ant, err := innGateApi.New(append([]innGateApi.Option{
+		innGateApi.WithBaseURL(s.URL + "/api/"),
+		innGateApi.WithPassword(s.Password),
+		innGateApi.WithHTTPClient(s.Client()),
+	}, opts...)...)
+	if(err != nil){ panic(err) }
+	return ant
+}
+
+//SetModule installs (or, with an empty version, removes) an API module so
+//tests can simulate gateways running other firmware.
+func (s *Server) SetModule(name, version string){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if(version == ""){
+		delete(s.modules, name)
+		return
+	}
+	s.modules[name] = version
+}
+
+//AddPlan adds a plan with default limits and returns its id.
+func (s *Server) AddPlan(name string) (id int64){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.plans{
+		if(p.id >= id){ id = p.id + 1 }
+	}
+	s.plans = append(s.plans, fakePlan{id : id, name : name, record : s.plans[0].record})
+	return id
+}
+
+//HandleOp answers op with h instead of the fake, e.g. to hold a reply back
+//or to send one the fake would not.  h sees every request for op, whatever
+//its api_password, and is not called under the fake's lock.
+func (s *Server) HandleOp(op string, h http.HandlerFunc){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if(s.handlers == nil){ s.handlers = map[string]http.HandlerFunc{} }
+	s.handlers[op] = h
+}
+
+//reply accumulates the output arguments of one API call.
+type reply struct{
+	op, version string
+	code        int64
+	err         string
+	fields      [][2]string
+}
+
+func (r *reply) set(key, value string){ r.fields = append(r.fields, [2]string{key, value}) }
+
+func (r *reply) fail(code int64, msg string){
+	r.code = code
+	r.err  = msg
+	r.fields = nil
+}
+
+func (s *Server) serveApi(w http.ResponseWriter, req *http.Request){
+	if(req.URL.Path != "/api/"){
+		http.NotFound(w, req)
+		return
+	}
+	q := req.URL.Query()
+
+	s.mu.Lock()
+	if h := s.handlers[q.Get("op")]; h != nil{
+		s.mu.Unlock()
+		h(w, req)
+		return
+	}
+	r := &reply{op : q.Get("op")}
+	r.version = s.modules[r.op]
+	switch {
+	case q.Get("api_password") != s.Password:
+		r.fail(2, "Incorrect api_password")
+	case r.version == "" && r.op != "api_version":
+		r.fail(3, "Incorrect op")
+	default:
+		s.dispatch(r, q)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "op = %s\n", r.op)
+	//api_version has no version line, and neither has an op without a module.
+	if(r.op != "api_version" && r.version != ""){ fmt.Fprintf(w, "version = %s\n", r.version) }
+	if(r.code == 0){
+		fmt.Fprintf(w, "result = ok\nresultcode = 0\n")
+	}else{
+		fmt.Fprintf(w, "result = error\nresultcode = %d\nerror = %s\n", r.code, r.err)
+	}
+	for _, f := range r.fields{
+		//Blank values are printed as "key = ", as the appliance does.
+		fmt.Fprintf(w, "%s = %s\n", f[0], f[1])
+	}
+}
+
+func (s *Server) dispatch(r *reply, q url.Values){
+	switch r.op{
+	case "api_version":
+		r.set("api_version", s.ApiVersion)
+	case "api_module":
+		v, ok := s.modules[q.Get("module")]
+		if(!ok){ r.fail(90, "Invalid module"); return }
+		r.version = v
+	case "api_modules":
+		names := make([]string, 0, len(s.modules))
+		for n := range s.modules{ names = append(names, n) }
+		sort.Strings(names)
+		for i, n := range names{ names[i] = n + " " + s.modules[n] }
+		r.set("count", strconv.Itoa(len(names)))
+		r.set("modules", strings.Join(names, "|"))
+	case "plan_get_all":
+		for i, p := range s.plans{
+			r.set("record_"+strconv.Itoa(i+1), strconv.FormatInt(p.id, 10)+"|"+p.record+"|"+p.name)
+		}
+	case "plan_get_id":
+		if(q.Get("plan_name") == ""){ r.fail(1, "More input arguments required"); return }
+		p := s.plan(q.Get("plan_name"), "")
+		if(p == nil){ r.fail(401, "Plan not found"); return }
+		r.set("plan_id", strconv.FormatInt(p.id, 10))
+	case "account_add":
+		s.accountAdd(r, q)
+	case "account_get":
+		s.accountGet(r, q)
+	case "account_get_all":
+		s.accountGetAll(r, q)
+	case "account_update":
+		s.accountUpdate(r, q)
+	case "account_delete":
+		s.accountDelete(r, q)
+	case "auth_authenticate":
+		if(q.Get("code") == "" && (q.Get("userid") == "" || q.Get("password") == "")){
+			r.fail(1, "More input arguments required"); return
+		}
+		s.authenticate(r, q)
+	case "auth_init":
+		s.authInit(r, q)
+	case "auth_login":
+		s.authLogin(r, q)
+	case "auth_logout":
+		s.authLogout(r, q)
+	case "auth_update":
+		if(q.Get("client_mac") == ""){ r.fail(1, "More input arguments required"); return }
+		if(q.Get("duration") == "" && q.Get("volume") == ""){ r.fail(90, "Argument values incorrect"); return }
+		for _, n := range []string{"duration", "volume"}{
+			if(q.Get(n) == "" || q.Get(n) == "unlimited"){ continue }
+			if _, err := strconv.ParseInt(q.Get(n), 10, 64); err != nil{ r.fail(90, "Argument values incorrect"); return }
+		}
+		if(s.sessionByMac(q.Get("client_mac")) == nil){ r.fail(98, "Critical error"); return }
+	case "sid_get":
+		ses := s.sessions[q.Get("sid")]
+		if(ses == nil){ r.fail(105, "Invalid sid"); return }
+		r.set("sid", ses.sid)
+		r.set("client_mac", ses.clientMac)
+		r.set("ppli", ses.ppli)
+		r.set("vlan", ses.vlan)
+		r.set("client_ip", ses.clientIp)
+		r.set("location_index", ses.locationIndex)
+		keys := make([]string, 0, len(ses.extra))
+		for k := range ses.extra{ keys = append(keys, k) }
+		sort.Strings(keys)
+		for _, k := range keys{ r.set(k, ses.extra[k]) }
+	case "publicip_get":
+		ses := s.sessions[q.Get("sid")]
+		if(q.Get("sid") == ""){ ses = s.sessionByMac(q.Get("client_mac")) }
+		if(ses == nil){ r.fail(4, "The sid could not be found"); return }
+		if(!ses.loggedIn){ r.fail(98, "Failed to get a public IP address"); return }
+		r.set("public_ip", "203.0.113." + strconv.Itoa(len(ses.sid)%200+1))
+	}
+}
+
+//plan finds a plan by name or by id.
+func (s *Server) plan(name, id string) (*fakePlan){
+	for i, p := range s.plans{
+		if((name != "" && p.name == name) || (id != "" && strconv.FormatInt(p.id, 10) == id)){ return &s.plans[i] }
+	}
+	return nil
+}
+
+func (s *Server) account(userid, code string) (*fakeAccount){
+	for _, a := range s.accounts{
+		if((userid != "" && a.userid == userid) || (code != "" && a.code == code)){ return a }
+	}
+	return nil
+}
+
+func (s *Server) sessionByMac(mac string) (*fakeSession){
+	for _, ses := range s.sessions{
+		if(strings.EqualFold(ses.clientMac, mac)){ return ses }
+	}
+	return nil
+}
+
+//generate produces a userid, password or code the way account_add does
+//when one is not supplied.
+func (s *Server) generate(format, length, prefix, suffix string, defaultFormat string) (string){
+	n, err := strconv.Atoi(length)
+	if(err != nil || n < 3){ n = 5 }
+	if(format == ""){ format = defaultFormat }
+	chars := "abcdefghijklmnopqrstuvwxyz0123456789"
+	switch format{
+	case "alpha":
+		chars = chars[:26]
+	case "num":
+		chars = chars[26:]
+	}
+	b := make([]byte, n)
+	for i := range b{ b[i] = chars[s.rand.Intn(len(chars))] }
+	return prefix + string(b) + suffix
+}
+
+func unixArg(v string) (int64, bool){
+	switch v{
+	case "":
+		return 0, true
+	case "now":
+		return time.Now().Unix(), true
+	}
+	t, err := strconv.ParseInt(v, 10, 64)
+	return t, err == nil
+}
+
+func (s *Server) accountAdd(r *reply, q url.Values){
+	if(q.Get("creator") == ""){ r.fail(1, "More input arguments required"); return }
+	//The appliance requires plan_id or plan_name.  The fake falls back to the
+	//first plan so that requests without one can still be exercised.
+	plan := &s.plans[0]
+	if(q.Get("plan_id") != "" || q.Get("plan_name") != ""){
+		plan = s.plan(q.Get("plan_name"), q.Get("plan_id"))
+		if(plan == nil){ r.fail(98, "Database error"); return }
+	}
+	count := int64(1)
+	if(q.Get("count") != ""){
+		var err error
+		count, err = strconv.ParseInt(q.Get("count"), 10, 64)
+		if(err != nil || count < 1 || count > 100){ r.fail(90, "An invalid value was provided for an input argument"); return }
+	}
+	validFrom, ok1 := unixArg(q.Get("valid_from"))
+	validUntil, ok2 := unixArg(q.Get("valid_until"))
+	if(!ok1 || !ok2){ r.fail(90, "An invalid value was provided for an input argument"); return }
+	loginMax := int64(0)
+	if(q.Get("login_max") != "" && q.Get("login_max") != "unlimited"){
+		var err error
+		loginMax, err = strconv.ParseInt(q.Get("login_max"), 10, 64)
+		if(err != nil || loginMax < 1){ r.fail(90, "An invalid value was provided for an input argument"); return }
+	}
+	sharingMax := int64(1)
+	if(q.Get("sharing_max") != ""){
+		var err error
+		sharingMax, err = strconv.ParseInt(q.Get("sharing_max"), 10, 64)
+		if(err != nil || sharingMax < 1){ r.fail(90, "An invalid value was provided for an input argument"); return }
+	}
+	zone, _ := strconv.ParseInt(q.Get("allowed_login_zone"), 10, 64)
+	accountType := q.Get("type")
+	if(accountType == ""){ accountType = "userid" }
+
+	var userids, passwords, codes []string
+	for i := int64(0); i < count; i++{
+		a := &fakeAccount{
+			accountType : accountType,
+			creator     : q.Get("creator"),
+			userid      : q.Get("userid"),
+			password    : q.Get("password"),
+			code        : q.Get("code"),
+			description : q.Get("description"),
+			plan        : plan.name,
+			billingId   : q.Get("billing_id"),
+			enabled     : true,
+			validFrom   : validFrom,
+			validUntil  : validUntil,
+			loginLimit  : loginMax > 0,
+			loginMax    : loginMax,
+			sharingMax  : sharingMax,
+			allowedLoginZone : zone,
+		}
+		a.createTime = time.Now()
+		a.updateTime = a.createTime
+		if(a.userid == "" || count > 1){
+			a.userid = s.generate(q.Get("userid_format"), q.Get("userid_length"), q.Get("userid_prefix"), q.Get("userid_suffix"), "alpha")
+		}
+		if(a.password == ""){
+			a.password = s.generate(q.Get("password_format"), q.Get("password_length"), "", "", "alnum")
+		}
+		if(a.code == "" || count > 1){
+			a.code = s.generate(q.Get("code_format"), q.Get("code_length"), q.Get("code_prefix"), q.Get("code_suffix"), "alnum")
+		}
+		if(s.account(a.userid, a.code) != nil){ r.fail(98, "Database error"); return }
+		s.accounts = append(s.accounts, a)
+		userids   = append(userids, a.userid)
+		passwords = append(passwords, a.password)
+		codes     = append(codes, a.code)
+	}
+	r.set("created", strconv.FormatInt(count, 10))
+	r.set("userids", strings.Join(userids, "|"))
+	r.set("passwords", strings.Join(passwords, "|"))
+	r.set("codes", strings.Join(codes, "|"))
+}
+
+func onOff(b bool) (string){ if(b){ return "on" }; return "off" }
+func yesNo(b bool) (string){ if(b){ return "yes" }; return "no" }
+
+func (s *Server) accountGet(r *reply, q url.Values){
+	var matched []*fakeAccount
+	switch {
+	case q.Get("userid") != "" || q.Get("code") != "":
+		if a := s.account(q.Get("userid"), q.Get("code")); a != nil{ matched = append(matched, a) }
+	case q.Get("client_mac") != "":
+		for _, a := range s.accounts{
+			for _, mac := range a.devices{
+				if(strings.EqualFold(mac, q.Get("client_mac"))){ matched = append(matched, a); break }
+			}
+		}
+	default:
+		r.fail(1, "More input arguments required"); return
+	}
+	if(len(matched) == 0){ r.fail(98, "Database error"); return }
+
+	//Each field is a pipe separated list with one entry per device that
+	//shares the account (or a single entry if nobody has used it yet).
+	cols := map[string][]string{}
+	order := []string{"userid", "code", "sharing_index", "client_mac", "description", "enabled",
+		"valid_from", "valid_until", "login_limit", "login_max", "login_count", "sharing_max",
+		"plan", "duration_balance", "volume_balance", "create_time", "update_time"}
+	for _, a := range matched{
+		devices := a.devices
+		if(len(devices) == 0){ devices = []string{""} }
+		for i, mac := range devices{
+			cols["userid"]        = append(cols["userid"], a.userid)
+			cols["code"]          = append(cols["code"], a.code)
+			cols["sharing_index"] = append(cols["sharing_index"], strconv.Itoa(i))
+			cols["client_mac"]    = append(cols["client_mac"], mac)
+			cols["description"]   = append(cols["description"], a.description)
+			cols["enabled"]       = append(cols["enabled"], yesNo(a.enabled))
+			cols["valid_from"]    = append(cols["valid_from"], rfcTime(a.validFrom))
+			cols["valid_until"]   = append(cols["valid_until"], rfcTime(a.validUntil))
+			cols["login_limit"]   = append(cols["login_limit"], onOff(a.loginLimit))
+			cols["login_max"]     = append(cols["login_max"], strconv.FormatInt(a.loginMax, 10))
+			cols["login_count"]   = append(cols["login_count"], strconv.FormatInt(a.loginCount, 10))
+			cols["sharing_max"]   = append(cols["sharing_max"], strconv.FormatInt(a.sharingMax, 10))
+			cols["plan"]          = append(cols["plan"], a.plan)
+			cols["duration_balance"] = append(cols["duration_balance"], "unlimited")
+			cols["volume_balance"]   = append(cols["volume_balance"], "unlimited")
+			cols["create_time"]   = append(cols["create_time"], a.createTime.Format(time.RFC1123Z))
+			cols["update_time"]   = append(cols["update_time"], a.updateTime.Format(time.RFC1123Z))
+		}
+	}
+	for _, k := range order{ r.set(k, strings.Join(cols[k], "|")) }
+}
+
+func rfcTime(unix int64) (string){
+	if(unix == 0){ return "" }
+	return time.Unix(unix, 0).Format(time.RFC1123Z)
+}
+
+func (s *Server) accountGetAll(r *reply, q url.Values){
+	between := func(v int64, start, end string) (bool){
+		if st, err := strconv.ParseInt(start, 10, 64); err == nil && v < st{ return false }
+		if en, err := strconv.ParseInt(end, 10, 64); err == nil && v > en{ return false }
+		return true
+	}
+	created := func(t time.Time, start, end string) (bool){
+		for i, arg := range []string{start, end}{
+			if(arg == ""){ continue }
+			var bound time.Time
+			var err error
+			for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-2", "2 Jan 2006"}{
+				if bound, err = time.ParseInLocation(layout, arg, time.Local); err == nil{ break }
+			}
+			if(err != nil){ continue }
+			if((i == 0 && t.Before(bound)) || (i == 1 && t.After(bound))){ return false }
+		}
+		return true
+	}
+	var records []string
+	for _, a := range s.accounts{
+		if(q.Get("creator") != "" && q.Get("creator") != a.creator){ continue }
+		if(q.Get("type") != "" && q.Get("type") != a.accountType){ continue }
+		if(q.Get("description") != "" && !strings.Contains(a.description, q.Get("description"))){ continue }
+		if(q.Get("plan_name") != "" && q.Get("plan_name") != a.plan){ continue }
+		if(!between(a.validFrom, q.Get("valid_from_start"), q.Get("valid_from_end"))){ continue }
+		if(!between(a.validUntil, q.Get("valid_until_start"), q.Get("valid_until_end"))){ continue }
+		if(!created(a.createTime, q.Get("created_start"), q.Get("created_end"))){ continue }
+		records = append(records, strings.Join([]string{
+			a.accountType, a.creator, a.userid, a.code, a.description, yesNo(a.enabled),
+			strconv.FormatInt(a.validFrom, 10), strconv.FormatInt(a.validUntil, 10),
+			onOff(a.loginLimit), strconv.FormatInt(a.loginMax, 10), strconv.FormatInt(a.loginCount, 10),
+			strconv.FormatInt(a.sharingMax, 10), a.plan,
+			a.createTime.Format("2006-01-02 15:04:05"), a.updateTime.Format("2006-01-02 15:04:05"),
+			a.accounting, a.billingId,
+		}, "|"))
+	}
+	//Firmware in the field answers an empty result with error 90 rather
+	//than a count of zero; the fake does the same.
+	if(len(records) == 0){ r.fail(90, "An invalid value was provided for an input argument"); return }
+	r.set("count", strconv.Itoa(len(records)))
+	r.set("header", "Type|Creator|Userid|Code|Description|Enable|Validfrom|Validuntil|Loginlimit|Loginmax|Logincount|Sharingmax|Usergroupname|Createtime|Updatetime|Accounting|billingID")
+	for i, rec := range records{ r.set("record_"+strconv.Itoa(i+1), rec) }
+}
+
+func (s *Server) accountUpdate(r *reply, q url.Values){
+	if(q.Get("userid") == "" && q.Get("code") == ""){ r.fail(1, "More input arguments required"); return }
+	a := s.account(q.Get("userid"), q.Get("code"))
+	if(a == nil){ r.fail(98, "Database error"); return }
+	bad := func(){ r.fail(90, "An invalid value was provided for an input argument") }
+	//valid_from is required if valid_until is set to a unix time.
+	if(q.Get("valid_until") != "" && q.Get("valid_from") == ""){ r.fail(1, "More input arguments required"); return }
+
+	if _, ok := q["password"]; ok{
+		a.password = q.Get("password")
+		if(a.password == ""){ a.password = s.generate(q.Get("password_format"), q.Get("password_length"), "", "", "alnum") }
+		r.set("password", a.password)
+	}
+	if _, ok := q["description"]; ok{ a.description = q.Get("description") }
+	if _, ok := q["valid_from"]; ok{
+		v, ok := unixArg(q.Get("valid_from"))
+		if(!ok){ bad(); return }
+		a.validFrom = v
+	}
+	if _, ok := q["valid_until"]; ok{
+		v, ok := unixArg(q.Get("valid_until"))
+		if(!ok){ bad(); return }
+		a.validUntil = v
+	}
+	switch q.Get("login_limit"){
+	case "on":
+		a.loginLimit = true
+	case "off":
+		a.loginLimit = false
+	case "":
+	default:
+		bad(); return
+	}
+	if(q.Get("login_max") != ""){
+		v, err := strconv.ParseInt(q.Get("login_max"), 10, 64)
+		if(err != nil || v < 1){ bad(); return }
+		a.loginMax = v
+	}
+	if(q.Get("sharing_max") != ""){
+		v, err := strconv.ParseInt(q.Get("sharing_max"), 10, 64)
+		if(err != nil || v < 2 || v <= a.sharingMax){ bad(); return }
+		a.sharingMax = v
+	}
+	if(q.Get("plan_id") != "" || q.Get("plan_name") != ""){
+		if(a.loginCount > 0){ bad(); return }
+		p := s.plan(q.Get("plan_name"), q.Get("plan_id"))
+		if(p == nil){ r.fail(98, "Database error"); return }
+		a.plan = p.name
+	}
+	if(q.Get("allowed_login_zone") != ""){
+		v, err := strconv.ParseInt(q.Get("allowed_login_zone"), 10, 64)
+		if(err != nil){ bad(); return }
+		a.allowedLoginZone = v
+	}
+	a.updateTime = time.Now()
+}
+
+func (s *Server) accountDelete(r *reply, q url.Values){
+	if(q.Get("userid") == "" && q.Get("code") == ""){ r.fail(1, "More input arguments required"); return }
+	doomed := map[*fakeAccount]bool{}
+	for _, id := range strings.Split(q.Get("userid"), "|"){
+		if a := s.account(id, ""); id != "" && a != nil{ doomed[a] = true }
+	}
+	for _, c := range strings.Split(q.Get("code"), "|"){
+		if a := s.account("", c); c != "" && a != nil{ doomed[a] = true }
+	}
+	if(len(doomed) == 0){ r.fail(98, "Database error"); return }
+	kept := s.accounts[:0]
+	for _, a := range s.accounts{
+		if(!doomed[a]){ kept = append(kept, a) }
+	}
+	s.accounts = kept
+	r.set("deleted", strconv.Itoa(len(doomed)))
+}
+
+//authenticate checks a code or userid/password pair and returns the
+//matching account, failing the reply if there is none.
+func (s *Server) authenticate(r *reply, q url.Values) (*fakeAccount){
+	if(q.Get("code") != ""){
+		a := s.account("", q.Get("code"))
+		if(a == nil){ r.fail(159, "Invalid access code") }
+		return a
+	}
+	if(q.Get("userid") != "" && q.Get("password") == ""){ r.fail(153, "Password must be provided"); return nil }
+	a := s.account(q.Get("userid"), "")
+	if(a == nil || a.password != q.Get("password")){ r.fail(160, "Invalid userid and/or password"); return nil }
+	return a
+}
+
+func (s *Server) authInit(r *reply, q url.Values){
+	for _, k := range []string{"client_mac", "client_ip", "location_index", "ppli"}{
+		if(q.Get(k) == ""){ r.fail(102, "Input arguments are invalid or insufficient"); return }
+	}
+	ses := s.sessionByMac(q.Get("client_mac"))
+	if(ses == nil || q.Get("new_sid") == "1"){
+		s.serial++
+		ses = &fakeSession{sid : fmt.Sprintf("%032x", s.rand.Int63()<<20|s.serial)}
+		s.sessions[ses.sid] = ses
+	}
+	ses.clientMac     = q.Get("client_mac")
+	ses.clientIp      = q.Get("client_ip")
+	ses.locationIndex = q.Get("location_index")
+	ses.ppli          = q.Get("ppli")
+	ses.vlan          = ""
+	if i := strings.LastIndex(ses.ppli, "."); i >= 0{ ses.vlan = ses.ppli[i+1:] }
+	ses.extra = map[string]string{}
+	for k := range q{
+		switch k{
+		case "op", "api_password", "client_mac", "client_ip", "location_index", "ppli", "new_sid":
+		default:
+			ses.extra[k] = q.Get(k)
+		}
+	}
+	r.set("sid", ses.sid)
+	r.set("client_mac", ses.clientMac)
+	r.set("client_ip", ses.clientIp)
+	r.set("ppli", ses.ppli)
+	r.set("vlan", ses.vlan)
+}
+
+func (s *Server) authLogin(r *reply, q url.Values){
+	var ses *fakeSession
+	if(q.Get("sid") != ""){
+		ses = s.sessions[q.Get("sid")]
+		if(ses == nil){ r.fail(150, "Authentication error"); return }
+		//login- prefixed extra fields given to auth_init act as inputs.
+		for k, v := range ses.extra{
+			if(strings.HasPrefix(k, "login-") && q.Get(k[6:]) == ""){ q.Set(k[6:], v) }
+		}
+	}else{
+		for _, k := range []string{"client_mac", "client_ip", "location_index", "ppli"}{
+			if(q.Get(k) == ""){ r.fail(1, "More input arguments required"); return }
+		}
+		ses = s.sessionByMac(q.Get("client_mac"))
+		if(ses == nil){
+			s.serial++
+			ses = &fakeSession{sid : fmt.Sprintf("%032x", s.rand.Int63()<<20|s.serial), clientMac : q.Get("client_mac"),
+				clientIp : q.Get("client_ip"), locationIndex : q.Get("location_index"), ppli : q.Get("ppli"), extra : map[string]string{}}
+			s.sessions[ses.sid] = ses
+		}
+	}
+	switch q.Get("mode"){
+	case "", "login":
+	case "relogin":
+		r.fail(166, "Cookie not found"); return
+	default:
+		r.fail(90, "Argument values incorrect"); return
+	}
+	if(q.Get("secret") != "" && q.Get("secret") != ses.extra["secret"]){ r.fail(158, "Secret does not match the secret provided to auth_init"); return }
+	if(q.Get("code") == "" && q.Get("userid") == ""){ r.fail(1, "More input arguments required"); return }
+	a := s.authenticate(r, q)
+	if(a == nil){ return }
+	now := time.Now().Unix()
+	switch {
+	case !a.enabled:
+		r.fail(161, "Account disabled"); return
+	case (a.validFrom != 0 && now < a.validFrom) || (a.validUntil != 0 && now > a.validUntil):
+		r.fail(162, "Account not yet valid or expired"); return
+	case a.loginLimit && a.loginMax > 0 && a.loginCount >= a.loginMax:
+		r.fail(164, "Maximum number of login reached"); return
+	}
+	known := false
+	for _, mac := range a.devices{
+		if(strings.EqualFold(mac, ses.clientMac)){ known = true }
+	}
+	if(!known){
+		if(int64(len(a.devices)) >= a.sharingMax){ r.fail(152, "Sharing limit exceeded"); return }
+		a.devices = append(a.devices, ses.clientMac)
+	}
+	a.loginCount++
+	ses.loggedIn = true
+	ses.code     = a.code
+	if(q.Get("sid") != ""){ r.set("sid", ses.sid) }
+	r.set("client_mac", ses.clientMac)
+	r.set("client_ip", ses.clientIp)
+	r.set("ppli", ses.ppli)
+	r.set("vlan", ses.vlan)
+}
+
+func (s *Server) authLogout(r *reply, q url.Values){
+	var ses *fakeSession
+	switch {
+	case q.Get("sid") != "":
+		ses = s.sessions[q.Get("sid")]
+	case q.Get("client_mac") != "":
+		ses = s.sessionByMac(q.Get("client_mac"))
+		if(ses == nil){ r.fail(122, "The device's MAC address is not found on the LAN network"); return }
+	default:
+		r.fail(1, "More input arguments required"); return
+	}
+	if(ses == nil || !ses.loggedIn){ r.fail(190, "Logout error"); return }
+	ses.loggedIn = false
+	r.set("accounting", "ok")
+	if(q.Get("sid") != ""){ r.set("sid", ses.sid) }
+	r.set("client_mac", ses.clientMac)
+}
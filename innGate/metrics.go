@@ -0,0 +1,252 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Metrics receives one observation per call to the gateway.  MemoryMetrics is
+//an implementation that keeps counters and histograms in memory; implement
+//Metrics yourself to feed another system.
+type Metrics interface{
+	//ObserveCall records a completed call.  resultcode is the gateway's
+	//resultcode, or "" when there was no parsable reply (err is set then).
+	ObserveCall(op string, latency time.Duration, resultcode string, bytes int, err error)
+	//ObserveRetry records that op was sent again.
+	ObserveRetry(op string)
+}
+
+//WithMetrics reports every call to m through MetricsMiddleware, installed
+//after all other middleware so that retries made by them are counted.
+func WithMetrics(m Metrics) (Option){ return func(api *Host){ api.metrics = m } }
+
+//MetricsMiddleware reports every call that passes it to m.  Every send of
+//the call after its first is reported as a retry, whether a middleware ahead
+//of it retried the same *Call or the Host sent it again itself (with a fresh
+//api_password, see CachedCredentials).
+func MetricsMiddleware(m Metrics) (Middleware){
+	return func(next Invoker) Invoker{
+		return func(ctx context.Context, call *Call) (*Reply, error){
+			sent := call.Attempt
+			start := time.Now()
+			reply, err := next(ctx, call)
+			
+			for i := max(sent, 1); i < call.Attempt; i++{ m.ObserveRetry(call.Op) }
+			code, bytes := "", 0
+			if(reply != nil){
+				code, _ = reply.Value("resultcode")
+				bytes = reply.Bytes
+			}
+			m.ObserveCall(call.Op, time.Since(start), code, bytes, err)
+			return reply, err
+		}
+	}
+}
+//////////////////////////////////////////////////////////
+
+//DefaultBuckets are the latency histogram buckets, in seconds, used by
+//NewMemoryMetrics when none are given.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//MemoryMetrics is a Metrics that keeps per-op counters and latency histograms
+//in memory.  Read them with Snapshot, or serve them to Prometheus with
+//WritePrometheus or as an http.Handler.  It is safe for concurrent use.
+//
+//Example:
+//  metrics := innGateApi.NewMemoryMetrics()
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"), innGateApi.WithMetrics(metrics))
+//  http.Handle("/metrics", metrics)
+type MemoryMetrics struct{
+	buckets []float64
+	mu      sync.Mutex
+	ops     map[string]*OpStats
+}
+
+//OpStats are the counters MemoryMetrics keeps for one op.
+type OpStats struct{
+	Requests  int64            //calls completed, successful or not
+	Errors    map[string]int64 //failed calls by resultcode; "transport" when there was no parsable reply
+	Retries   int64
+	BytesRead int64
+	Latency   Histogram
+}
+
+//Histogram is a latency histogram.  Counts[i] is the number of observations
+//no larger than Buckets[i] seconds (and larger than Buckets[i-1]); the last
+//element of Counts holds those larger than every bucket.
+type Histogram struct{
+	Buckets []float64
+	Counts  []int64
+	Sum     float64 //seconds
+	Count   int64
+}
+
+//NewMemoryMetrics returns an empty MemoryMetrics whose histograms use buckets
+//(in seconds, ascending), or DefaultBuckets if none are given.
+func NewMemoryMetrics(buckets ...float64) (m *MemoryMetrics){
+	if(len(buckets) == 0){ buckets = DefaultBuckets }
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &MemoryMetrics{buckets : buckets, ops : make(map[string]*OpStats)}
+}
+
+//stats returns the stats of op, creating them if needed.  m.mu must be held.
+func (m *MemoryMetrics) stats(op string) (stats *OpStats){
+	stats = m.ops[op]
+	if(stats == nil){
+		stats = &OpStats{Errors : make(map[string]int64), Latency : Histogram{Buckets : m.buckets, Counts : make([]int64, len(m.buckets)+1)}}
+		m.ops[op] = stats
+	}
+	return stats
+}
+
+func (m *MemoryMetrics) ObserveCall(op string, latency time.Duration, resultcode string, bytes int, err error){
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats(op)
+	stats.Requests++
+	stats.BytesRead += int64(bytes)
+	switch {
+	case resultcode != "" && resultcode != "0":
+		stats.Errors[resultcode]++
+	case resultcode == "" && err != nil:
+		stats.Errors["transport"]++
+	}
+	
+	seconds := latency.Seconds()
+	i := sort.SearchFloat64s(stats.Latency.Buckets, seconds)
+	stats.Latency.Counts[i]++
+	stats.Latency.Sum += seconds
+	stats.Latency.Count++
+}
+
+func (m *MemoryMetrics) ObserveRetry(op string){
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(op).Retries++
+}
+
+//Snapshot returns a copy of the stats of every op seen so far.
+func (m *MemoryMetrics) Snapshot() (ops map[string]OpStats){
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops = make(map[string]OpStats, len(m.ops))
+	for op, stats := range m.ops{
+		c := *stats
+		c.Errors = make(map[string]int64, len(stats.Errors))
+		for code, n := range stats.Errors{ c.Errors[code] = n }
+		c.Latency.Counts = append([]int64(nil), stats.Latency.Counts...)
+		ops[op] = c
+	}
+	return ops
+}
+//////////////////////////////////////////////////////////
+
+//WritePrometheus writes the metrics in the Prometheus text exposition format:
+//
+//  inngate_requests_total{op}
+//  inngate_errors_total{op,resultcode}
+//  inngate_retries_total{op}
+//  inngate_read_bytes_total{op}
+//  inngate_request_duration_seconds{op} (histogram)
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) (err error){
+	snapshot := m.Snapshot()
+	ops := make([]string, 0, len(snapshot))
+	for op := range snapshot{ ops = append(ops, op) }
+	sort.Strings(ops)
+	
+	p := &promWriter{w : w}
+	p.header("inngate_requests_total", "counter", "InnGate API calls completed, by op.")
+	for _, op := range ops{ p.sample("inngate_requests_total", snapshot[op].Requests, "op", op) }
+	
+	p.header("inngate_errors_total", "counter", "InnGate API calls that failed, by op and resultcode (\"transport\" when there was no reply).")
+	for _, op := range ops{
+		codes := make([]string, 0, len(snapshot[op].Errors))
+		for code := range snapshot[op].Errors{ codes = append(codes, code) }
+		sort.Strings(codes)
+		for _, code := range codes{ p.sample("inngate_errors_total", snapshot[op].Errors[code], "op", op, "resultcode", code) }
+	}
+	
+	p.header("inngate_retries_total", "counter", "InnGate API calls sent again, by op.")
+	for _, op := range ops{ p.sample("inngate_retries_total", snapshot[op].Retries, "op", op) }
+	
+	p.header("inngate_read_bytes_total", "counter", "Bytes of InnGate API replies read, by op.")
+	for _, op := range ops{ p.sample("inngate_read_bytes_total", snapshot[op].BytesRead, "op", op) }
+	
+	p.header("inngate_request_duration_seconds", "histogram", "Latency of InnGate API calls, by op.")
+	for _, op := range ops{
+		h := snapshot[op].Latency
+		var cumulative int64
+		for i, le := range h.Buckets{
+			cumulative += h.Counts[i]
+			p.sample("inngate_request_duration_seconds_bucket", cumulative, "op", op, "le", strconv.FormatFloat(le, 'g', -1, 64))
+		}
+		p.sample("inngate_request_duration_seconds_bucket", h.Count, "op", op, "le", "+Inf")
+		p.sample("inngate_request_duration_seconds_sum", h.Sum, "op", op)
+		p.sample("inngate_request_duration_seconds_count", h.Count, "op", op)
+	}
+	return p.err
+}
+
+//ServeHTTP serves the metrics to a Prometheus scraper.
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, req *http.Request){
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+//promWriter writes Prometheus text lines, keeping the first error.
+type promWriter struct{
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) header(name, kind, help string){
+	if(p.err != nil){ return }
+	_, p.err = fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+//sample writes one line; labels alternate names and values.
+func (p *promWriter) sample(name string, value interface{}, labels ...string){
+	if(p.err != nil){ return }
+	line := name + "{"
+	for i := 0; i+1 < len(labels); i += 2{
+		if(i > 0){ line += "," }
+		line += labels[i] + "=\"" + labelEscaper.Replace(labels[i+1]) + "\""
+	}
+	line += "} "
+	switch v := value.(type){
+	case int64:
+		line += strconv.FormatInt(v, 10)
+	case float64:
+		line += strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	_, p.err = io.WriteString(p.w, line + "\n")
+}
@@ -0,0 +1,53 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi_test
+
+import (
+	"context"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"sync/atomic"
+	"testing"
+)
+
+//TestMetricsCountPasswordRetry rotates the api_password behind a
+//CachedCredentials: the call sent with the stale password and sent again with
+//the fresh one is one request and one retry.
+func TestMetricsCountPasswordRetry(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	var current atomic.Value
+	current.Store(gw.Password)
+	creds := &innGateApi.CachedCredentials{Source : innGateApi.CredentialsFunc(func(ctx context.Context) (string, error){
+		return current.Load().(string), nil
+	})}
+	metrics := innGateApi.NewMemoryMetrics()
+	ant := gw.Host(innGateApi.WithCredentials(creds), innGateApi.WithMetrics(metrics))
+	
+	if _, err := ant.ApiVersion(); err != nil{ t.Fatal(err) }
+	gw.Password = "rotated"
+	current.Store("rotated")
+	resp, err := ant.ApiVersion()
+	if(err != nil){ t.Fatal(err) }
+	if err := resp.Err(); err != nil{ t.Fatalf("the rotated password was not picked up: %v", err) }
+	
+	stats := metrics.Snapshot()["api_version"]
+	if(stats.Requests != 2 || stats.Retries != 1){ t.Errorf("got %d requests and %d retries, want 2 and 1", stats.Requests, stats.Retries) }
+}
@@ -0,0 +1,150 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"github.com/secesh/gantlabs"
+	"context"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Call is one request to the API, as seen by middleware.  Params holds every
+//parameter except api_password and op, which are added when the request is
+//sent.
+type Call struct{
+	Op      string
+	Params  url.Values
+	Attempt int //times the call has been sent to the gateway so far
+	
+	//stream, if set, is handed each record_N field as it is read, and the
+	//reply goes on only while it returns true; the records are left out of
+	//the Reply.  Accounts sets it.
+	stream func(record antlabs.Field) (more bool)
+}
+
+//Reply is the gateway's answer to a Call, before it is decoded into the op's
+//response type.  A Reply may come with an error when the gateway answered
+//with something that could not be parsed.
+type Reply struct{
+	Fields []antlabs.Field
+	Bytes  int //length of the reply body
+	
+	streamed int //record_N fields handed to the Call's stream
+}
+
+//Value returns the value of the first field of the reply called name.
+func (reply *Reply) Value(name string) (value string, ok bool){
+	for _, field := range reply.Fields{
+		if(field.Name == name){ return field.Value, true }
+	}
+	return "", false
+}
+
+//Invoker sends a Call and returns the Reply.
+type Invoker func(ctx context.Context, call *Call) (*Reply, error)
+
+//Middleware wraps an Invoker, e.g. to log, measure or alter calls.  It sees
+//every op a Host performs.  Install it with WithMiddleware.
+//
+//Example:
+//  stamp := func(next innGateApi.Invoker) innGateApi.Invoker{
+//    return func(ctx context.Context, call *innGateApi.Call) (*innGateApi.Reply, error){
+//      if(call.Op == "account_add"){ call.Params.Set("billing_id", "frontdesk") }
+//      return next(ctx, call)
+//    }
+//  }
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"), innGateApi.WithMiddleware(stamp))
+type Middleware func(next Invoker) Invoker
+
+//chain wraps invoke in mw, so that mw[0] sees a call first.
+func chain(invoke Invoker, mw []Middleware) (Invoker){
+	for i := len(mw)-1; i >= 0; i--{ invoke = mw[i](invoke) }
+	return invoke
+}
+//////////////////////////////////////////////////////////
+
+//LoggingMiddleware logs every call to logger: the op, its latency, the
+//resultcode and the names of the parameters sent.  Values are never logged;
+//an access code or MAC address identifies a guest as well as a password does.
+//Calls that succeed are logged at debug level; calls that fail, or that the
+//gateway answers with a resultcode other than 0, at warn level.
+//WithLogger installs it as the outermost middleware.
+func LoggingMiddleware(logger *slog.Logger) (Middleware){
+	return func(next Invoker) Invoker{
+		return func(ctx context.Context, call *Call) (*Reply, error){
+			start := time.Now()
+			reply, err := next(ctx, call)
+			
+			attrs := []slog.Attr{
+				slog.String("op", call.Op),
+				slog.Duration("latency", time.Since(start)),
+				slog.Any("params", paramNames(call.Params)),
+			}
+			level := slog.LevelDebug
+			if(reply != nil){
+				if code, ok := reply.Value("resultcode"); ok{
+					attrs = append(attrs, slog.String("resultcode", code))
+					if(code != "0"){ level = slog.LevelWarn }
+				}
+			}
+			if(err != nil){
+				logger.LogAttrs(ctx, slog.LevelWarn, "innGate api request failed", append(attrs, slog.Any("err", err))...)
+			}else{
+				logger.LogAttrs(ctx, level, "innGate api request", attrs...)
+			}
+			return reply, err
+		}
+	}
+}
+
+//Redacted replaces the value of secret parameters in Redact's result.
+const Redacted = "REDACTED"
+
+//Redact returns a copy of params with the value of every secret parameter
+//replaced by Redacted, for middleware of your own that logs values.  Secret
+//parameters are api_password and the other passwords, secret, the access
+//code, and the card fields (cc_number, cc_csc, ...).
+func Redact(params url.Values) (redacted url.Values){
+	redacted = make(url.Values, len(params))
+	for name, values := range params{
+		if(isSecret(name)){
+			values = []string{Redacted}
+		}
+		redacted[name] = append([]string(nil), values...)
+	}
+	return redacted
+}
+
+func isSecret(name string) (bool){
+	name = strings.ToLower(name)
+	return name == "secret" || name == "code" || strings.Contains(name, "password") || strings.HasPrefix(name, "cc_") || strings.Contains(name, "card")
+}
+
+//paramNames returns the names of params, sorted.
+func paramNames(params url.Values) (names []string){
+	names = make([]string, 0, len(params))
+	for name := range params{ names = append(names, name) }
+	sort.Strings(names)
+	return names
+}
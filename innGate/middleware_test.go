@@ -0,0 +1,49 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi_test
+
+import (
+	"bytes"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+//TestLoggingNamesOnly checks that the logger writes parameter names but no
+//values: not the api_password, and not the guest's access code.
+func TestLoggingNamesOnly(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.Password = "s3cret-pw"
+	var out bytes.Buffer
+	ant := gw.Host(innGateApi.WithLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level : slog.LevelDebug}))))
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Code : "k2m4p", Description : "room 101"})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	
+	log := out.String()
+	for _, value := range []string{"k2m4p", "room 101", gw.Password}{
+		if(strings.Contains(log, value)){ t.Errorf("the log holds %q:\n%s", value, log) }
+	}
+	if(!strings.Contains(log, "code")){ t.Errorf("the log does not name the code parameter:\n%s", log) }
+}
@@ -0,0 +1,218 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"iter"
+	"sync"
+)
+
+//Mock is a Client for unit tests.  Every call is recorded, then handed to the
+//matching Func field; a nil Func answers with an empty, successful reply for
+//the op.  Accounts is recorded as account_get_all, and without AccountsFunc
+//lists what AccountGetAllFunc answers, filtered as Host.Accounts filters.  A Mock is safe for concurrent use, but
+//its Func fields must be set before it is shared.
+//
+//Example:
+//  mock := &innGateApi.Mock{}
+//  mock.AccountGetFunc = func(request innGateApi.AccountGetRequest) (*innGateApi.AccountGetResponse, error){
+//    common := innGateApi.ResponseCommon{Op : "account_get", Result : "ok"}
+//    account := innGateApi.Account{UserId : request.UserId, UserGroupName : "Guest"}
+//    return &innGateApi.AccountGetResponse{ResponseCommon : common, Accounts : []innGateApi.Account{account}}, nil
+//  }
+//  err := expire(mock, "abcde")
+//  calls := mock.CallsTo("account_update")
+//  if(err != nil || len(calls) != 1){ t.Fatal(err, calls) }
+//  fmt.Println(calls[0].Request.(innGateApi.AccountUpdateRequest).UserId)
+type Mock struct{
+	ApiVersionFunc       func() (*VersionResponse, error)
+	ModuleFunc           func(request ModuleRequest) (*ModuleResponse, error)
+	ModulesFunc          func() (*ModulesResponse, error)
+	AuthAuthenticateFunc func(request AuthAuthenticateRequest) (*AuthAuthenticateResponse, error)
+	AuthInitFunc         func(request AuthInitRequest) (*AuthInitResponse, error)
+	AuthLoginFunc        func(request AuthLoginRequest) (*AuthLoginResponse, error)
+	AuthLogoutFunc       func(request AuthLogoutRequest) (*AuthLogoutResponse, error)
+	AuthUpdateFunc       func(request AuthUpdateRequest) (*AuthUpdateResponse, error)
+	SidGetFunc           func(request SidGetRequest) (*SidGetResponse, error)
+	PublicIpFunc         func(request PublicIpRequest) (*PublicIpResponse, error)
+	AccountAddFunc       func(request AccountAddRequest) (*AccountAddResponse, error)
+	AccountGetFunc       func(request AccountGetRequest) (*AccountGetResponse, error)
+	AccountGetAllFunc    func(arg interface{}) (*AccountGetAllResponse, error)
+	AccountsFunc         func(arg interface{}) (iter.Seq2[Account, error])
+	AccountUpdateFunc    func(request AccountUpdateRequest) (*AccountUpdateResponse, error)
+	AccountDeleteFunc    func(request AccountDeleteRequest) (*AccountDeleteResponse, error)
+	PlanAllFunc          func() (*PlanAllResponse, error)
+	PlanIdFunc           func(request PlanIdRequest) (*PlanIdResponse, error)
+	
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+//MockCall is one call made to a Mock.  Op is the API op (e.g. "account_add")
+//and Request is the argument the method was given, or nil for ops that take
+//none.
+type MockCall struct{
+	Op      string
+	Request interface{}
+}
+
+//Calls returns every call made so far, oldest first.
+func (mock *Mock) Calls() (calls []MockCall){
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	return append([]MockCall(nil), mock.calls...)
+}
+
+//CallsTo returns the calls made so far for one op, oldest first.
+func (mock *Mock) CallsTo(op string) (calls []MockCall){
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	for _, call := range mock.calls{
+		if(call.Op == op){ calls = append(calls, call) }
+	}
+	return calls
+}
+
+//Reset forgets the calls recorded so far.
+func (mock *Mock) Reset(){
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	mock.calls = nil
+}
+
+func (mock *Mock) record(op string, request interface{}){
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	mock.calls = append(mock.calls, MockCall{Op : op, Request : request})
+}
+
+//okReply is the common part of a successful reply to op.
+func okReply(op string) (common ResponseCommon){
+	return ResponseCommon{Op : op, Result : "ok"}
+}
+//////////////////////////////////////////////////////////
+
+func (mock *Mock) ApiVersion() (*VersionResponse, error){
+	mock.record("api_version", nil)
+	if(mock.ApiVersionFunc != nil){ return mock.ApiVersionFunc() }
+	return &VersionResponse{ResponseCommon : okReply("api_version")}, nil
+}
+func (mock *Mock) Module(request ModuleRequest) (*ModuleResponse, error){
+	mock.record("api_module", request)
+	if(mock.ModuleFunc != nil){ return mock.ModuleFunc(request) }
+	return &ModuleResponse{ResponseCommon : okReply("api_module")}, nil
+}
+func (mock *Mock) Modules() (*ModulesResponse, error){
+	mock.record("api_modules", nil)
+	if(mock.ModulesFunc != nil){ return mock.ModulesFunc() }
+	return &ModulesResponse{ResponseCommon : okReply("api_modules"), Modules : map[string]float64{}}, nil
+}
+//////////////////////////////////////////////////////////
+
+func (mock *Mock) AuthAuthenticate(request AuthAuthenticateRequest) (*AuthAuthenticateResponse, error){
+	mock.record("auth_authenticate", request)
+	if(mock.AuthAuthenticateFunc != nil){ return mock.AuthAuthenticateFunc(request) }
+	return &AuthAuthenticateResponse{ResponseCommon : okReply("auth_authenticate")}, nil
+}
+func (mock *Mock) AuthInit(request AuthInitRequest) (*AuthInitResponse, error){
+	mock.record("auth_init", request)
+	if(mock.AuthInitFunc != nil){ return mock.AuthInitFunc(request) }
+	return &AuthInitResponse{ResponseCommon : okReply("auth_init")}, nil
+}
+func (mock *Mock) AuthLogin(request AuthLoginRequest) (*AuthLoginResponse, error){
+	mock.record("auth_login", request)
+	if(mock.AuthLoginFunc != nil){ return mock.AuthLoginFunc(request) }
+	return &AuthLoginResponse{ResponseCommon : okReply("auth_login")}, nil
+}
+func (mock *Mock) AuthLogout(request AuthLogoutRequest) (*AuthLogoutResponse, error){
+	mock.record("auth_logout", request)
+	if(mock.AuthLogoutFunc != nil){ return mock.AuthLogoutFunc(request) }
+	return &AuthLogoutResponse{ResponseCommon : okReply("auth_logout")}, nil
+}
+func (mock *Mock) AuthUpdate(request AuthUpdateRequest) (*AuthUpdateResponse, error){
+	mock.record("auth_update", request)
+	if(mock.AuthUpdateFunc != nil){ return mock.AuthUpdateFunc(request) }
+	return &AuthUpdateResponse{ResponseCommon : okReply("auth_update")}, nil
+}
+func (mock *Mock) SidGet(request SidGetRequest) (*SidGetResponse, error){
+	mock.record("sid_get", request)
+	if(mock.SidGetFunc != nil){ return mock.SidGetFunc(request) }
+	return &SidGetResponse{ResponseCommon : okReply("sid_get")}, nil
+}
+func (mock *Mock) PublicIp(request PublicIpRequest) (*PublicIpResponse, error){
+	mock.record("publicip_get", request)
+	if(mock.PublicIpFunc != nil){ return mock.PublicIpFunc(request) }
+	return &PublicIpResponse{ResponseCommon : okReply("publicip_get")}, nil
+}
+//////////////////////////////////////////////////////////
+
+func (mock *Mock) AccountAdd(request AccountAddRequest) (*AccountAddResponse, error){
+	mock.record("account_add", request)
+	if(mock.AccountAddFunc != nil){ return mock.AccountAddFunc(request) }
+	return &AccountAddResponse{ResponseCommon : okReply("account_add")}, nil
+}
+func (mock *Mock) AccountGet(request AccountGetRequest) (*AccountGetResponse, error){
+	mock.record("account_get", request)
+	if(mock.AccountGetFunc != nil){ return mock.AccountGetFunc(request) }
+	return &AccountGetResponse{ResponseCommon : okReply("account_get")}, nil
+}
+func (mock *Mock) AccountGetAll(arg interface{}) (*AccountGetAllResponse, error){
+	mock.record("account_get_all", arg)
+	if(mock.AccountGetAllFunc != nil){ return mock.AccountGetAllFunc(arg) }
+	return &AccountGetAllResponse{ResponseCommon : okReply("account_get_all")}, nil
+}
+func (mock *Mock) Accounts(arg interface{}) (iter.Seq2[Account, error]){
+	mock.record("account_get_all", arg)
+	if(mock.AccountsFunc != nil){ return mock.AccountsFunc(arg) }
+	return func(yield func(Account, error) (bool)){
+		if(mock.AccountGetAllFunc == nil){ return }
+		resp, err := mock.AccountGetAllFunc(arg)
+		if(err == nil && resp != nil){ err = resp.Err() }
+		if(err != nil){ yield(Account{}, err); return }
+		if(resp == nil){ return }
+		request := accountGetAllRequest(arg)
+		for _, account := range resp.Accounts{
+			if(!request.Match(account)){ continue }
+			if(!yield(account, nil)){ return }
+		}
+	}
+}
+func (mock *Mock) AccountUpdate(request AccountUpdateRequest) (*AccountUpdateResponse, error){
+	mock.record("account_update", request)
+	if(mock.AccountUpdateFunc != nil){ return mock.AccountUpdateFunc(request) }
+	return &AccountUpdateResponse{ResponseCommon : okReply("account_update")}, nil
+}
+func (mock *Mock) AccountDelete(request AccountDeleteRequest) (*AccountDeleteResponse, error){
+	mock.record("account_delete", request)
+	if(mock.AccountDeleteFunc != nil){ return mock.AccountDeleteFunc(request) }
+	return &AccountDeleteResponse{ResponseCommon : okReply("account_delete")}, nil
+}
+//////////////////////////////////////////////////////////
+
+func (mock *Mock) PlanAll() (*PlanAllResponse, error){
+	mock.record("plan_get_all", nil)
+	if(mock.PlanAllFunc != nil){ return mock.PlanAllFunc() }
+	return &PlanAllResponse{ResponseCommon : okReply("plan_get_all")}, nil
+}
+func (mock *Mock) PlanId(request PlanIdRequest) (*PlanIdResponse, error){
+	mock.record("plan_get_id", request)
+	if(mock.PlanIdFunc != nil){ return mock.PlanIdFunc(request) }
+	return &PlanIdResponse{ResponseCommon : okReply("plan_get_id")}, nil
+}
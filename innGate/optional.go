@@ -0,0 +1,51 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+//Optional is a request field that is sent only when it has been set, so a
+//zero, false or blank can be sent on purpose.  The zero value is unset.
+//
+//Example:
+//  ant.AccountUpdate(innGateApi.AccountUpdateRequest{Code : "k2m4p", Description : innGateApi.Set("")})
+type Optional[T any] struct{
+	value T
+	set   bool
+}
+
+//Set returns an Optional holding v.
+func Set[T any](v T) (Optional[T]){ return Optional[T]{value : v, set : true} }
+
+//Int returns an Optional holding n; it saves writing Set[int64](n).
+func Int(n int64) (Optional[int64]){ return Set(n) }
+
+//Bool returns an Optional holding b.
+func Bool(b bool) (Optional[bool]){ return Set(b) }
+
+//Get returns the value and whether it is set.
+func (o Optional[T]) Get() (v T, ok bool){ return o.value, o.set }
+
+//IsSet reports whether o holds a value.
+func (o Optional[T]) IsSet() (bool){ return o.set }
+
+//Or returns the value, or def if o is unset.
+func (o Optional[T]) Or(def T) (T){
+	if(!o.set){ return def }
+	return o.value
+}
@@ -0,0 +1,119 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//ErrUnknownPlan is returned, wrapped, when a plan named in a request does not
+//exist on the gateway.
+var ErrUnknownPlan = errors.New("innGateApi: unknown plan")
+
+//plans returns the gateway's plans from plan_get_all, which the Cache given
+//to WithCache, if any, keeps.  refresh skips the cache and asks the gateway.
+func (api *Host) plans(refresh bool) (plans []Plan, err error){
+	if(refresh && api.cache != nil){ api.cache.Invalidate("plan_get_all") }
+	resp, err := api.PlanAll()
+	if(err != nil){ return nil, err }
+	if err := resp.Err(); err != nil{ return nil, err }
+	return resp.Plans, nil
+}
+
+//findPlan returns the first plan match accepts.  If none does and the plans
+//came from the cache, they are fetched again once: a plan may have been added
+//since they were cached.
+func (api *Host) findPlan(match func(Plan) bool) (plan Plan, err error){
+	for _, refresh := range []bool{false, true}{
+		if(refresh && api.cache == nil){ break }
+		plans, err := api.plans(refresh)
+		if(err != nil){ return Plan{}, err }
+		for _, p := range plans{
+			if(match(p)){ return p, nil }
+		}
+	}
+	return Plan{}, ErrUnknownPlan
+}
+
+//PlanByName returns the plan called name, or an error wrapping
+//ErrUnknownPlan.
+func (api *Host) PlanByName(name string) (plan Plan, err error){
+	plan, err = api.findPlan(func(p Plan) bool{ return p.Name == name })
+	if(errors.Is(err, ErrUnknownPlan)){ return Plan{}, fmt.Errorf("%w %q", ErrUnknownPlan, name) }
+	return plan, err
+}
+
+//PlanById returns the plan with the given id, or an error wrapping
+//ErrUnknownPlan.
+func (api *Host) PlanById(id int64) (plan Plan, err error){
+	plan, err = api.findPlan(func(p Plan) bool{ return p.Id == id })
+	if(errors.Is(err, ErrUnknownPlan)){ return Plan{}, fmt.Errorf("%w with id %d", ErrUnknownPlan, id) }
+	return plan, err
+}
+
+//planIds remembers the plan IDs seen to exist, so that accounts added to a
+//plan by ID do not list every plan each time; it is shared by copies of a
+//Host.  IDs are never reused, so one that is known stays valid until its plan
+//is deleted, when the gateway refuses the account instead.
+type planIds struct{
+	mu    sync.Mutex
+	known map[int64]bool
+}
+
+func (p *planIds) has(id int64) (bool){
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.known[id]
+}
+
+func (p *planIds) add(id int64){
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if(p.known == nil){ p.known = make(map[int64]bool) }
+	p.known[id] = true
+}
+
+//resolvePlan turns the plan named by id or name (at most one may be set) into
+//its ID, checking that it exists.  ok is false if neither is set.  A name is
+//looked up with plan_get_id; an ID not seen before with plan_get_all.
+func (api *Host) resolvePlan(id int64, name string) (planId int64, ok bool, err error){
+	switch {
+	case id != 0 && name != "":
+		return 0, false, errors.New("innGateApi: give PlanId or PlanName, not both.")
+	case name != "":
+		resp, err := api.PlanId(PlanIdRequest{Name : name})
+		if(err != nil){ return 0, false, err }
+		err = resp.Err()
+		if(errors.Is(err, ErrUnknownPlan)){ return 0, false, fmt.Errorf("%w %q", ErrUnknownPlan, name) }
+		if(err != nil){ return 0, false, err }
+		id = resp.Id
+	case id != 0:
+		if(!api.planIds.has(id)){
+			_, err := api.PlanById(id)
+			if(err != nil){ return 0, false, err }
+		}
+	default:
+		return 0, false, nil
+	}
+	api.planIds.add(id)
+	return id, true, nil
+}
@@ -0,0 +1,87 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi_test
+
+import (
+	"errors"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"testing"
+)
+
+//TestPlanAddedAfterLookup adds a plan on the gateway after the plans were
+//cached: an account on it must still be created, the cache being skipped for
+//the second look.
+func TestPlanAddedAfterLookup(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host(innGateApi.WithCache(innGateApi.NewCache(nil)))
+	
+	if _, err := ant.PlanByName("Guest"); err != nil{ t.Fatal(err) }
+	id := gw.AddPlan("New")
+	plan, err := ant.PlanByName("New")
+	if(err != nil){ t.Fatal(err) }
+	if(plan.Id != id){ t.Errorf("got plan %d, want %d", plan.Id, id) }
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", PlanName : "New"})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	
+	_, err = ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", PlanName : "Missing"})
+	if(!errors.Is(err, innGateApi.ErrUnknownPlan)){ t.Errorf("got %v, want ErrUnknownPlan", err) }
+}
+
+//TestResultcode401 checks that only plan_get_id's 401 means an unknown plan.
+func TestResultcode401(t *testing.T){
+	for op, want := range map[string]bool{"plan_get_id" : true, "account_get" : false, "auth_login" : false}{
+		resp, err := innGateApi.Decode(op, "op = " + op + "\nversion = 1.0\nresult = failed\nresultcode = 401\nerror = Unauthorized\n")
+		if(err != nil){ t.Fatal(err) }
+		err = resp.(interface{ Err() error }).Err()
+		if(errors.Is(err, innGateApi.ErrUnknownPlan) != want){ t.Errorf("%s: got %v, want ErrUnknownPlan %v", op, err, want) }
+	}
+}
+
+//TestPlanLookups adds accounts by plan name and ID, which must not list every
+//plan per account: a name is looked up with plan_get_id, and an ID is
+//checked with plan_get_all only the first time.
+func TestPlanLookups(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	metrics := innGateApi.NewMemoryMetrics()
+	ant := gw.Host(innGateApi.WithMetrics(metrics))
+	
+	for _, request := range []innGateApi.AccountAddRequest{
+		{Creator : "admin", PlanName : "Throttled"},
+		{Creator : "admin", PlanName : "Throttled"},
+		{Creator : "admin", PlanId : 1},
+		{Creator : "admin", PlanId : 1},
+	}{
+		resp, err := ant.AccountAdd(request)
+		if(err != nil){ t.Fatal(err) }
+		if err := resp.Err(); err != nil{ t.Fatal(err) }
+	}
+	stats := metrics.Snapshot()
+	if(stats["plan_get_id"].Requests != 2 || stats["plan_get_all"].Requests != 1){
+		t.Errorf("got %d plan_get_id and %d plan_get_all, want 2 and 1", stats["plan_get_id"].Requests, stats["plan_get_all"].Requests)
+	}
+	
+	_, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", PlanId : 99})
+	if(!errors.Is(err, innGateApi.ErrUnknownPlan)){ t.Errorf("got %v, want ErrUnknownPlan", err) }
+}
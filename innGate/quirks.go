@@ -0,0 +1,191 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"github.com/secesh/gantlabs"
+	"context"
+	"strconv"
+	"sync"
+)
+
+//Quirk is a known misbehaviour of some InnGate firmware, and its workaround.
+//A quirk applies to replies to Op whose module version lies within
+//[MinModuleVersion, MaxModuleVersion] from gateways whose API version lies
+//within [MinApiVersion, MaxApiVersion]; a zero bound is open.  Fix rewrites
+//such a reply into what a correct gateway would have sent.
+type Quirk struct{
+	Name        string
+	Description string
+	Op          string
+	
+	MinApiVersion, MaxApiVersion       float64
+	MinModuleVersion, MaxModuleVersion float64
+	
+	Fix func(reply *Reply)
+}
+
+//Quirk names, for WithQuirk.
+const (
+	QuirkEmptyAccountList = "account_get_all-empty-is-error-90"
+	QuirkDeleteNoMatch    = "account_delete-no-match-is-error-98"
+)
+
+//KnownQuirks is the registry of quirks.  All of them are worked around by
+//default on the firmware they apply to; switch them individually with
+//WithQuirk, or change or add one with WithCustomQuirk.  Both were seen on
+//InnGate 3 with the 1.0 and 1.01 account modules the API guide (release 1.01)
+//documents; later modules are assumed fixed until a gateway shows otherwise.
+//
+//(The api_module reply carrying "version" once instead of twice is handled by
+//ModuleResponse itself and needs no switch.)
+var KnownQuirks = []Quirk{
+	{
+		Name        : QuirkEmptyAccountList,
+		Description : "account_get_all answers a filter that matches no account with error 90 " +
+			"(invalid value) instead of an empty list; it is turned into an empty list.  A truly " +
+			"invalid filter value is then indistinguishable from no match.",
+		Op          : "account_get_all",
+		MinModuleVersion : 1.0,
+		MaxModuleVersion : 1.01,
+		Fix         : func(reply *Reply){
+			if(reply.code() != "90"){ return }
+			reply.succeed()
+			reply.set("count", "0")
+		},
+	},
+	{
+		Name        : QuirkDeleteNoMatch,
+		Description : "account_delete answers with error 98 (database error) when none of the " +
+			"accounts exists; it is turned into success with deleted = 0.  A real database error " +
+			"is then indistinguishable from no match.",
+		Op          : "account_delete",
+		MinModuleVersion : 1.0,
+		MaxModuleVersion : 1.01,
+		Fix         : func(reply *Reply){
+			if(reply.code() != "98"){ return }
+			reply.succeed()
+			reply.set("deleted", "0")
+		},
+	},
+}
+
+//WithQuirk switches the workaround for the quirk called name on or off.
+func WithQuirk(name string, enabled bool) (Option){
+	return func(api *Host){
+		if(api.quirksOff == nil){ api.quirksOff = make(map[string]bool) }
+		api.quirksOff[name] = !enabled
+	}
+}
+
+//WithCustomQuirk adds q to the quirks worked around, or replaces the known
+//quirk of the same name, e.g. to widen its versions to a gateway's firmware.
+//
+//Example:
+//  q := innGateApi.KnownQuirks[0]
+//  q.MaxModuleVersion = 1.2
+//  ant, err := innGateApi.New(innGateApi.WithHost("ant.example.com"), innGateApi.WithPassword("secret"), innGateApi.WithCustomQuirk(q))
+func WithCustomQuirk(q Quirk) (Option){
+	return func(api *Host){ api.quirksAdded = append(api.quirksAdded, q) }
+}
+
+//apiVersionCache remembers the gateway's API version for the quirks; it is
+//shared by copies of a Host.
+type apiVersionCache struct{
+	mu      sync.Mutex
+	version float64
+	known   bool
+}
+
+//quirks is the middleware that applies the enabled quirks.
+func (api *Host) quirks(next Invoker) (Invoker){
+	byName := make(map[string]Quirk)
+	var names []string
+	for _, q := range append(KnownQuirks[:len(KnownQuirks):len(KnownQuirks)], api.quirksAdded...){
+		if _, ok := byName[q.Name]; !ok{ names = append(names, q.Name) }
+		byName[q.Name] = q
+	}
+	byOp := make(map[string][]Quirk)
+	for _, name := range names{
+		q := byName[name]
+		if(!api.quirksOff[q.Name]){ byOp[q.Op] = append(byOp[q.Op], q) }
+	}
+	return func(ctx context.Context, call *Call) (*Reply, error){
+		reply, err := next(ctx, call)
+		if(err != nil || len(byOp[call.Op]) == 0){ return reply, err }
+		
+		moduleVersion, _ := strconv.ParseFloat(reply.value("version"), 64)
+		for _, q := range byOp[call.Op]{
+			if(!within(moduleVersion, q.MinModuleVersion, q.MaxModuleVersion)){ continue }
+			if(q.MinApiVersion != 0 || q.MaxApiVersion != 0){
+				apiVersion, ok := api.apiVersion(ctx)
+				if(!ok || !within(apiVersion, q.MinApiVersion, q.MaxApiVersion)){ continue }
+			}
+			q.Fix(reply)
+		}
+		return reply, nil
+	}
+}
+
+//apiVersion asks the gateway for its API version once.
+func (api *Host) apiVersion(ctx context.Context) (version float64, ok bool){
+	c := api.versionCache
+	if(c == nil){ return 0, false }
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if(!c.known){
+		resp, err := api.WithContext(ctx).ApiVersion()
+		if(err != nil || resp.Err() != nil){ return 0, false }
+		c.version, c.known = resp.ApiVersion, true
+	}
+	return c.version, true
+}
+
+func within(v, min, max float64) (bool){
+	return (min == 0 || v >= min) && (max == 0 || v <= max)
+}
+//////////////////////////////////////////////////////////
+
+//value returns the value of the field called name, or "".
+func (reply *Reply) value(name string) (string){
+	v, _ := reply.Value(name)
+	return v
+}
+
+func (reply *Reply) code() (string){ return reply.value("resultcode") }
+
+//set replaces the value of the field called name, or adds the field.
+func (reply *Reply) set(name, value string){
+	for i := range reply.Fields{
+		if(reply.Fields[i].Name == name){ reply.Fields[i].Value = value; return }
+	}
+	reply.Fields = append(reply.Fields, antlabs.Field{Name : name, Value : value})
+}
+
+//succeed turns an error reply into a successful one.
+func (reply *Reply) succeed(){
+	fields := reply.Fields[:0]
+	for _, field := range reply.Fields{
+		if(field.Name != "error"){ fields = append(fields, field) }
+	}
+	reply.Fields = fields
+	reply.set("result", "ok")
+	reply.set("resultcode", "0")
+}
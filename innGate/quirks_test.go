@@ -0,0 +1,73 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"testing"
+)
+
+//TestQuirkVersions lists no accounts from the fake, which answers error 90
+//like the firmware, with the account_get_all module at various versions: the
+//workaround applies within its versions, bounds included, and only there.
+func TestQuirkVersions(t *testing.T){
+	wider := innGateApi.KnownQuirks[0]
+	wider.MaxModuleVersion = 2.0
+	later := innGateApi.KnownQuirks[0]
+	later.MinApiVersion = 3.5
+	for _, c := range []struct{
+		version string
+		opts    []innGateApi.Option
+		want    int64
+	}{
+		{"0.9", nil, 90},
+		{"1.0", nil, 0},
+		{"1.01", nil, 0},
+		{"1.02", nil, 90},
+		{"2.0", nil, 90},
+		{"1.0", []innGateApi.Option{innGateApi.WithQuirk(innGateApi.QuirkEmptyAccountList, false)}, 90},
+		{"2.0", []innGateApi.Option{innGateApi.WithCustomQuirk(wider)}, 0},
+		{"1.0", []innGateApi.Option{innGateApi.WithCustomQuirk(later)}, 90}, //the fake's API is 3.0
+	}{
+		gw := innGateTest.NewServer()
+		gw.SetModule("account_get_all", c.version)
+		resp, err := gw.Host(c.opts...).AccountGetAll(innGateApi.AccountQuery{}.Creator("nobody"))
+		gw.Close()
+		if(err != nil){ t.Fatal(err) }
+		if(resp.Resultcode != c.want){ t.Errorf("module %s with %d options: got resultcode %d, want %d", c.version, len(c.opts), resp.Resultcode, c.want) }
+	}
+}
+
+//TestCustomQuirk adds a quirk of its own for a newer account_delete module.
+func TestCustomQuirk(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.SetModule("account_delete", "2.0")
+	
+	resp, err := gw.Host().AccountDelete(innGateApi.AccountDeleteRequest{Code : []string{"k2m4p"}})
+	if(err != nil){ t.Fatal(err) }
+	if(resp.Resultcode != 98){ t.Errorf("without the quirk: got resultcode %d, want 98", resp.Resultcode) }
+	
+	q := innGateApi.KnownQuirks[1]
+	q.Name, q.MinModuleVersion, q.MaxModuleVersion = "account_delete-2.0-no-match", 2.0, 0
+	resp, err = gw.Host(innGateApi.WithCustomQuirk(q)).AccountDelete(innGateApi.AccountDeleteRequest{Code : []string{"k2m4p"}})
+	if(err != nil){ t.Fatal(err) }
+	if err := resp.Err(); err != nil || resp.Deleted != 0{ t.Errorf("with the quirk: got %v, deleted %d; want success, 0", err, resp.Deleted) }
+}
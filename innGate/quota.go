@@ -0,0 +1,98 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+//ByteSize is an amount of data, in bytes.
+type ByteSize int64
+
+const(
+	Byte     ByteSize = 1
+	Kilobyte          = 1024*Byte
+	Megabyte          = 1024*Kilobyte //the unit of a plan's volume
+	Gigabyte          = 1024*Megabyte
+)
+
+//UnlimitedVolume and UnlimitedDuration stand for the gateway's "unlimited".
+//They are the largest values of their types, so a quota compares as more
+//than any amount.
+const(
+	UnlimitedVolume   ByteSize      = math.MaxInt64
+	UnlimitedDuration time.Duration = math.MaxInt64
+)
+
+//String formats b in the largest unit that keeps it at least 1, e.g. "1.5MB".
+func (b ByteSize) String() (string){
+	switch {
+	case b == UnlimitedVolume: return "unlimited"
+	case b >= Gigabyte:        return strconv.FormatFloat(float64(b)/float64(Gigabyte), 'f', -1, 64) + "GB"
+	case b >= Megabyte:        return strconv.FormatFloat(float64(b)/float64(Megabyte), 'f', -1, 64) + "MB"
+	case b >= Kilobyte:        return strconv.FormatFloat(float64(b)/float64(Kilobyte), 'f', -1, 64) + "KB"
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+//errQuotaRange is returned for a quota too large to represent.
+var errQuotaRange = errors.New("value out of range")
+
+//scale multiplies n, a count of some unit, into that unit's base, failing
+//rather than overflowing.
+func scale(n, unit int64) (int64, error){
+	if(n < 0 || n > math.MaxInt64/unit){ return 0, errQuotaRange }
+	return n*unit, nil
+}
+
+//formatMinutes encodes d for the gateway in minutes, or as "unlimited".
+func formatMinutes(d time.Duration) (string){
+	if(d == UnlimitedDuration){ return "unlimited" }
+	return strconv.FormatInt(int64(d/time.Minute), 10)
+}
+
+//formatBytes encodes b for the gateway in bytes, or as "unlimited".
+func formatBytes(b ByteSize) (string){
+	if(b == UnlimitedVolume){ return "unlimited" }
+	return strconv.FormatInt(int64(b), 10)
+}
+
+//parseDuration decodes a duration the gateway writes in minutes, or as
+//"unlimited".
+func parseDuration(s string) (d time.Duration, err error){
+	if(s == "unlimited"){ return UnlimitedDuration, nil }
+	n, err := strconv.ParseInt(s, 10, 64)
+	if(err != nil){ return 0, err }
+	n, err = scale(n, int64(time.Minute))
+	return time.Duration(n), err
+}
+
+//parseVolume decodes a volume the gateway writes as a count of unit, or as
+//"unlimited".
+func parseVolume(s string, unit ByteSize) (b ByteSize, err error){
+	if(s == "unlimited"){ return UnlimitedVolume, nil }
+	n, err := strconv.ParseInt(s, 10, 64)
+	if(err != nil){ return 0, err }
+	n, err = scale(n, int64(unit))
+	return ByteSize(n), err
+}
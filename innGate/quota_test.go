@@ -0,0 +1,60 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+//TestAuthUpdateUnlimited gives a device unlimited time and volume, which must
+//be sent as the gateway's "unlimited"; a part of a minute is still refused.
+func TestAuthUpdateUnlimited(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	var sent []string
+	record := func(next innGateApi.Invoker) innGateApi.Invoker{
+		return func(ctx context.Context, call *innGateApi.Call) (*innGateApi.Reply, error){
+			if(call.Op == "auth_update"){ sent = append(sent, call.Params.Get("duration"), call.Params.Get("volume")) }
+			return next(ctx, call)
+		}
+	}
+	ant := gw.Host(innGateApi.WithMiddleware(record))
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	mac := innGateApi.MustParseMac("00:11:25:87:0B:7D")
+	login, err := ant.AuthLogin(innGateApi.AuthLoginRequest{ClientMac : mac, ClientIp : netip.MustParseAddr("10.0.0.7"), Ppli : "ppli", LocationIndex : 1, Code : add.Codes[0]})
+	if(err != nil){ t.Fatal(err) }
+	if err := login.Err(); err != nil{ t.Fatal(err) }
+	
+	resp, err := ant.AuthUpdate(innGateApi.AuthUpdateRequest{ClientMac : mac, Duration : innGateApi.Set(innGateApi.UnlimitedDuration), Volume : innGateApi.Set(innGateApi.UnlimitedVolume)})
+	if(err != nil){ t.Fatal(err) }
+	if err := resp.Err(); err != nil{ t.Fatal(err) }
+	if(len(sent) != 2 || sent[0] != "unlimited" || sent[1] != "unlimited"){ t.Errorf("sent duration and volume %q, want unlimited", sent) }
+	
+	_, err = ant.AuthUpdate(innGateApi.AuthUpdateRequest{ClientMac : mac, Duration : innGateApi.Set(90*time.Second)})
+	if(!errors.Is(err, innGateApi.ErrInvalidRequest)){ t.Errorf("got %v, want ErrInvalidRequest", err) }
+}
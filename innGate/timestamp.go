@@ -0,0 +1,73 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+//listLayout is how account_get_all writes create and update times: on the
+//gateway's clock, without a zone.
+const listLayout = "2006-01-02 15:04:05"
+
+//WithLocation sets the gateway's time zone (default time.Local).  Times the
+//gateway writes without a zone, such as account_get_all's create and update
+//times, are read in loc, and every time in a reply is returned in loc.
+func WithLocation(loc *time.Location) (Option){ return func(api *Host){ api.location = loc } }
+
+//ParseTime decodes a time in any of the forms the gateway writes: unix
+//seconds (account_get_all), RFC 1123 with a zone (account_get) or
+//"2006-01-02 15:04:05" on the gateway's clock, which is read in loc (nil for
+//time.Local).  A blank time, or 0, is no time at all: the zero time.Time.
+func ParseTime(s string, loc *time.Location) (t time.Time, err error){
+	if(loc == nil){ loc = time.Local }
+	if(s == "" || s == "0"){ return time.Time{}, nil }
+	if(isDigits(s)){
+		n, err := strconv.ParseInt(s, 10, 64)
+		if(err != nil){ return time.Time{}, err }
+		return time.Unix(n, 0).In(loc), nil
+	}
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, listLayout}{
+		t, err = time.ParseInLocation(layout, s, loc)
+		if(err == nil){ return t.In(loc), nil }
+	}
+	return time.Time{}, errors.New("unknown time format " + strconv.Quote(s))
+}
+
+//formatTime encodes t as the unix seconds requests take; the zero time is
+//blank, which removes a ValidFrom or ValidUntil.
+func formatTime(t time.Time) (string){
+	if(t.IsZero()){ return "" }
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+//NoTime is the Optional time that removes an account's ValidFrom or
+//ValidUntil in an AccountUpdateRequest.  As an AccountAddRequest's
+//ValidUntil it says the new account never expires, as leaving it unset does.
+//
+//Example:
+//  ant.AccountUpdate(innGateApi.AccountUpdateRequest{Code : "k2m4p", ValidUntil : innGateApi.NoTime()})
+func NoTime() (Optional[time.Time]){ return Set(time.Time{}) }
+
+//Expires returns when the account expires; ok is false if it never does,
+//i.e. the gateway wrote a blank valid_until and ValidUntil is zero.
+func (a Account) Expires() (until time.Time, ok bool){ return a.ValidUntil, !a.ValidUntil.IsZero() }
@@ -0,0 +1,88 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+//TestParseTime reads every form the gateway writes, the zoneless one on the
+//gateway's clock.
+func TestParseTime(t *testing.T){
+	sgt := time.FixedZone("SGT", 8*60*60)
+	want := time.Date(2009, 6, 25, 14, 52, 20, 0, sgt)
+	for _, s := range []string{"1245912740", "Thu, 25 Jun 2009 14:52:20 +0800", "2009-06-25 14:52:20"}{
+		got, err := innGateApi.ParseTime(s, sgt)
+		if(err != nil){ t.Errorf("%s: %v", s, err); continue }
+		if(!got.Equal(want)){ t.Errorf("%s: got %v, want %v", s, got, want) }
+	}
+	if got, err := innGateApi.ParseTime("", sgt); err != nil || !got.IsZero(){ t.Errorf("blank: got %v, %v; want the zero time", got, err) }
+	if _, err := innGateApi.ParseTime("25/06/2009", sgt); err == nil{ t.Errorf("25/06/2009: no error") }
+}
+
+//TestWithLocation reads account_get_all's zoneless create_time, and sends the
+//created range, on a gateway whose clock is 8 hours ahead of UTC.
+func TestWithLocation(t *testing.T){
+	sgt := time.FixedZone("SGT", 8*60*60)
+	var createdStart string
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.HandleOp("account_get_all", func(w http.ResponseWriter, r *http.Request){
+		createdStart = r.URL.Query().Get("created_start")
+		fmt.Fprint(w, "op = account_get_all\nversion = 1.0\nresult = ok\nresultcode = 0\ncount = 1\n" +
+			"header = Type|Creator|Userid|Code|Description|Enable|Validfrom|Validuntil|Loginlimit|Loginmax|Logincount|Sharingmax|Usergroupname|Createtime|Updatetime|Accounting|billingID\n" +
+			"record_1 = code|admin||k2m4p||yes|0|0|off|0|0|1|Guest|2009-06-25 14:52:20|2009-06-25 14:52:20||\n")
+	})
+	ant := gw.Host(innGateApi.WithLocation(sgt))
+	
+	resp, err := ant.AccountGetAll(innGateApi.AccountQuery{}.Created(time.Date(2009, 6, 25, 0, 0, 0, 0, time.UTC), time.Time{}))
+	if(err != nil){ t.Fatal(err) }
+	if(createdStart != "2009-06-25 08:00:00"){ t.Errorf("sent created_start %q, want the gateway's 2009-06-25 08:00:00", createdStart) }
+	if(len(resp.Accounts) != 1){ t.Fatalf("got %d accounts, want 1", len(resp.Accounts)) }
+	if want := time.Date(2009, 6, 25, 6, 52, 20, 0, time.UTC); !resp.Accounts[0].CreateTime.Equal(want){
+		t.Errorf("got create_time %v, want %v", resp.Accounts[0].CreateTime, want)
+	}
+}
+
+//TestAccountAddNoTime sends no valid_until for an account that never expires.
+func TestAccountAddNoTime(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	sent := map[string]bool{}
+	record := func(next innGateApi.Invoker) innGateApi.Invoker{
+		return func(ctx context.Context, call *innGateApi.Call) (*innGateApi.Reply, error){
+			if(call.Op == "account_add"){ _, sent["valid_until"] = call.Params["valid_until"] }
+			return next(ctx, call)
+		}
+	}
+	ant := gw.Host(innGateApi.WithMiddleware(record))
+	resp, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Code : "k2m4p", ValidUntil : innGateApi.NoTime()})
+	if(err != nil){ t.Fatal(err) }
+	if err := resp.Err(); err != nil{ t.Fatal(err) }
+	if(sent["valid_until"]){ t.Errorf("NoTime() sent a blank valid_until") }
+	get, err := ant.AccountGet(innGateApi.AccountGetRequest{Code : "k2m4p"})
+	if(err != nil){ t.Fatal(err) }
+	if _, ok := get.Accounts[0].Expires(); ok{ t.Errorf("the account expires") }
+}
@@ -0,0 +1,95 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+//Tracer starts spans; it is small enough to adapt to OpenTelemetry or any
+//other tracing library.  WithTracer installs it.
+//
+//Example (OpenTelemetry):
+//  type otelTracer struct{ trace.Tracer }
+//  func (t otelTracer) Start(ctx context.Context, name string) (context.Context, innGateApi.Span){
+//    ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//    return ctx, otelSpan{span}
+//  }
+//  type otelSpan struct{ trace.Span }
+//  func (s otelSpan) SetAttribute(key string, value interface{}){ s.SetAttributes(attribute.String(key, fmt.Sprint(value))) }
+//  func (s otelSpan) RecordError(err error){ s.Span.RecordError(err); s.SetStatus(codes.Error, err.Error()) }
+//  func (s otelSpan) End(){ s.Span.End() }
+type Tracer interface{
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+//Span is one traced op.
+type Span interface{
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+//WithTracer traces every op with t through TracingMiddleware, installed ahead
+//of all other middleware.  Use Host.WithContext to make the spans children
+//of the caller's.
+func WithTracer(t Tracer) (Option){ return func(api *Host){ api.tracer = t } }
+
+//TracingMiddleware wraps every call in a span named "innGate " + op, with the
+//attributes:
+//  inngate.op          the op
+//  server.address      the gateway's host
+//  inngate.resultcode  the gateway's resultcode
+//  inngate.retries     times the call was sent again
+//  inngate.records     number of record_N fields in the reply
+//A failed call, or one answered with a resultcode other than 0, is recorded
+//as an error on the span.
+func TracingMiddleware(t Tracer, host string) (Middleware){
+	return func(next Invoker) Invoker{
+		return func(ctx context.Context, call *Call) (*Reply, error){
+			ctx, span := t.Start(ctx, "innGate " + call.Op)
+			defer span.End()
+			span.SetAttribute("inngate.op", call.Op)
+			span.SetAttribute("server.address", host)
+			
+			reply, err := next(ctx, call)
+			
+			if(call.Attempt > 1){ span.SetAttribute("inngate.retries", call.Attempt-1) }
+			if(reply != nil){
+				records := reply.streamed
+				for _, field := range reply.Fields{
+					if(strings.HasPrefix(field.Name, "record_")){ records++ }
+				}
+				span.SetAttribute("inngate.records", records)
+				if code, ok := reply.Value("resultcode"); ok{
+					span.SetAttribute("inngate.resultcode", code)
+					if(code != "0" && err == nil){
+						msg, _ := reply.Value("error")
+						span.RecordError(errors.New("resultcode " + code + ": " + msg))
+					}
+				}
+			}
+			if(err != nil){ span.RecordError(err) }
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+//recordingTracer keeps every span it starts.
+type recordingTracer struct{
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, innGateApi.Span){
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordingSpan{name : name, attrs : map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+//last returns the most recent span.
+func (t *recordingTracer) last() (*recordingSpan){
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if(len(t.spans) == 0){ return &recordingSpan{attrs : map[string]interface{}{}} }
+	return t.spans[len(t.spans)-1]
+}
+
+type recordingSpan struct{
+	name  string
+	attrs map[string]interface{}
+	errs  []error
+	ended bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}){ s.attrs[key] = value }
+func (s *recordingSpan) RecordError(err error){ s.errs = append(s.errs, err) }
+func (s *recordingSpan) End(){ s.ended = true }
+
+//attr returns the attribute key as a string, or "" if it was not set.
+func (s *recordingSpan) attr(key string) (string){
+	v, ok := s.attrs[key]
+	if(!ok){ return "" }
+	return fmt.Sprint(v)
+}
+
+//TestTracingAttributes checks the attributes of the spans for a plain op, a
+//failed one, a record list and a streamed record list.
+func TestTracingAttributes(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	gw.AddPlan("Extra")
+	tracer := &recordingTracer{}
+	ant := gw.Host(innGateApi.WithTracer(tracer))
+	host := strings.Split(strings.TrimPrefix(gw.URL, "https://"), ":")[0]
+	
+	if _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, Count : 2}); err != nil{ t.Fatal(err) }
+	ant.PlanId(innGateApi.PlanIdRequest{Name : "No such plan"})
+	unknown := tracer.last()
+	if _, err := ant.PlanAll(); err != nil{ t.Fatal(err) }
+	plans := tracer.last()
+	for _, err := range ant.Accounts(nil){
+		if(err != nil){ t.Fatal(err) }
+	}
+	streamed := tracer.last()
+	
+	for _, c := range []struct{
+		span                        *recordingSpan
+		op, resultcode, records     string
+		failed                      bool
+	}{
+		{unknown,  "plan_get_id",     "401", "0", true},
+		{plans,    "plan_get_all",    "0",   "3", false},
+		{streamed, "account_get_all", "0",   "2", false},
+	}{
+		s := c.span
+		if(s.name != "innGate " + c.op || !s.ended){ t.Errorf("%s: got span %q (ended %v)", c.op, s.name, s.ended) }
+		for key, want := range map[string]string{"inngate.op" : c.op, "server.address" : host,
+			"inngate.resultcode" : c.resultcode, "inngate.records" : c.records, "inngate.retries" : ""}{
+			if got := s.attr(key); got != want{ t.Errorf("%s: %s is %q, want %q", c.op, key, got, want) }
+		}
+		if((len(s.errs) > 0) != c.failed){ t.Errorf("%s: recorded errors %v, want failed %v", c.op, s.errs, c.failed) }
+	}
+}
+
+//TestTracingRetries rotates the api_password behind a CachedCredentials: the
+//call sent again with the fresh password is one span with one retry.
+func TestTracingRetries(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	var current atomic.Value
+	current.Store(gw.Password)
+	creds := &innGateApi.CachedCredentials{Source : innGateApi.CredentialsFunc(func(ctx context.Context) (string, error){
+		return current.Load().(string), nil
+	})}
+	tracer := &recordingTracer{}
+	ant := gw.Host(innGateApi.WithCredentials(creds), innGateApi.WithTracer(tracer))
+	
+	if _, err := ant.ApiVersion(); err != nil{ t.Fatal(err) }
+	gw.Password = "rotated"
+	current.Store("rotated")
+	if _, err := ant.ApiVersion(); err != nil{ t.Fatal(err) }
+	
+	if(len(tracer.spans) != 2){ t.Fatalf("got %d spans, want 2", len(tracer.spans)) }
+	s := tracer.last()
+	if got := s.attr("inngate.retries"); got != "1"{ t.Errorf("inngate.retries is %q, want 1", got) }
+	if got := s.attr("inngate.resultcode"); got != "0"{ t.Errorf("inngate.resultcode is %q, want 0", got) }
+	if(len(s.errs) != 0){ t.Errorf("recorded errors %v after a successful retry", s.errs) }
+}
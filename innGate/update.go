@@ -0,0 +1,158 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+//ErrUpdateApplied is returned, wrapped, by ModifyAccount when account_update
+//went through but the account could not be read again; do not retry the
+//change.
+var ErrUpdateApplied = errors.New("innGateApi: account updated, but not read again")
+
+//AccountChange is what ModifyAccount did.  Request is the account_update that
+//was sent, or nil if modify changed nothing; then nothing was sent and After
+//is Before.
+type AccountChange struct{
+	Before   Account
+	After    Account
+	Request  *AccountUpdateRequest
+	Response *AccountUpdateResponse
+}
+
+//ModifyAccount reads the account request names (by UserId, Code or a
+//ClientMac only one account has been used from), hands a copy of it to
+//modify, and sends account_update with only the fields modify changed; then
+//it reads the account again for After.  If only that read fails, the error
+//wraps ErrUpdateApplied and change is returned too, with After as modify left
+//it.
+//
+//modify may change Description, ValidFrom, ValidUntil, LoginLimit (off for
+//unlimited logins), LoginMax (0 for UnlimitedLogins, as the gateway writes
+//it), SharingMax and UserGroupName (the plan); a change to any other field is
+//a *ValidationError and nothing is sent.  A zero ValidFrom or ValidUntil
+//removes it; without ValidUntil the account never expires.  account_update
+//needs a start time with an expiry, so changing ValidUntil also sends
+//ValidFrom, or now if the account has none.
+//
+//Example:
+//  change, err := innGateApi.ModifyAccount(ant, innGateApi.AccountGetRequest{Code : "k2m4p"}, func(account *innGateApi.Account) (error){
+//    account.ValidUntil = account.ValidUntil.Add(24*time.Hour)
+//    return nil
+//  })
+//  if(err != nil){ panic(err) }
+//  fmt.Println("Expires", change.After.ValidUntil, "instead of", change.Before.ValidUntil)
+func ModifyAccount(client AccountClient, request AccountGetRequest, modify func(account *Account) (error)) (change *AccountChange, err error){
+	change = &AccountChange{}
+	change.Before, err = getAccount(client, request)
+	if(err != nil){ return nil, err }
+	
+	change.After = change.Before
+	change.After.Sharing = make([]Sharing, len(change.Before.Sharing))
+	for i, share := range change.Before.Sharing{
+		change.After.Sharing[i] = Sharing{Index : share.Index, ClientMac : slices.Clone(share.ClientMac)}
+	}
+	err = modify(&change.After)
+	if(err != nil){ return nil, err }
+	
+	change.Request, err = accountDiff(change.Before, change.After)
+	if(err != nil || change.Request == nil){ return change, err }
+	
+	change.Response, err = client.AccountUpdate(*change.Request)
+	if(err != nil){ return nil, err }
+	if err := change.Response.Err(); err != nil{ return nil, err }
+	
+	after, err := getAccount(client, AccountGetRequest{UserId : change.Request.UserId, Code : change.Request.Code})
+	if(err != nil){ return change, fmt.Errorf("%w: %w", ErrUpdateApplied, err) }
+	change.After = after
+	return change, nil
+}
+
+//getAccount reads one account with account_get; a ClientMac shared by
+//several accounts is an error.
+func getAccount(client AccountClient, request AccountGetRequest) (account Account, err error){
+	resp, err := client.AccountGet(request)
+	if(err != nil){ return Account{}, err }
+	if err := resp.Err(); err != nil{ return Account{}, err }
+	switch len(resp.Accounts){
+	case 0:  return Account{}, errors.New("innGateApi: account_get returned no account.")
+	case 1:  return resp.Accounts[0], nil
+	}
+	return Account{}, errors.New("innGateApi: account_get matched " + strconv.Itoa(len(resp.Accounts)) + " accounts; name one by UserId or Code.")
+}
+
+func (a Sharing) equal(b Sharing) (bool){ return a.Index == b.Index && bytes.Equal(a.ClientMac, b.ClientMac) }
+
+//accountDiff returns the account_update that turns before into after, or nil
+//if they are the same.
+func accountDiff(before, after Account) (request *AccountUpdateRequest, err error){
+	v := validator{op : "account_update"}
+	v.check(after.Type == before.Type, "Type", "cannot be changed")
+	v.check(after.Creator == before.Creator, "Creator", "cannot be changed")
+	v.check(after.UserId == before.UserId, "UserId", "cannot be changed")
+	v.check(after.Code == before.Code, "Code", "cannot be changed")
+	v.check(after.Enable == before.Enable, "Enable", "cannot be changed")
+	v.check(after.LoginCount == before.LoginCount, "LoginCount", "cannot be changed")
+	v.check(after.CreateTime.Equal(before.CreateTime), "CreateTime", "cannot be changed")
+	v.check(after.UpdateTime.Equal(before.UpdateTime), "UpdateTime", "cannot be changed")
+	v.check(after.Accounting == before.Accounting, "Accounting", "cannot be changed")
+	v.check(after.BillingId == before.BillingId, "BillingId", "cannot be changed")
+	v.check(slices.EqualFunc(after.Sharing, before.Sharing, Sharing.equal), "Sharing", "cannot be changed")
+	v.check(after.DurationBalance == before.DurationBalance, "DurationBalance", "cannot be changed")
+	v.check(after.VolumeBalance == before.VolumeBalance, "VolumeBalance", "cannot be changed")
+	v.check(!after.ValidFrom.IsZero() || before.ValidFrom.IsZero() || after.ValidUntil.IsZero(), "ValidFrom", "cannot be cleared while ValidUntil is set")
+	v.check(after.LoginMax == before.LoginMax || after.LoginMax >= 0, "LoginMax", "must be 0 (unlimited) or more")
+	err = v.err()
+	if(err != nil){ return nil, err }
+	
+	request = &AccountUpdateRequest{}
+	changed := false
+	if(after.Description != before.Description){ request.Description, changed = Set(after.Description), true }
+	if(!after.ValidFrom.Equal(before.ValidFrom)){ request.ValidFrom, changed = Set(after.ValidFrom), true }
+	if(!after.ValidUntil.Equal(before.ValidUntil)){
+		request.ValidUntil, changed = Set(after.ValidUntil), true
+		if(!after.ValidUntil.IsZero()){
+			validFrom := after.ValidFrom
+			if(validFrom.IsZero()){ validFrom = time.Now() }
+			request.ValidFrom = Set(validFrom)
+		}
+	}
+	if(after.LoginLimit != before.LoginLimit){ request.LoginLimit, changed = Bool(after.LoginLimit), true }
+	if(after.LoginMax != before.LoginMax){
+		request.LoginMax, changed = UnlimitedLogins, true
+		if(after.LoginMax > 0){ request.LoginMax = MustLogins(after.LoginMax) }
+	}
+	if(after.SharingMax != before.SharingMax){ request.SharingMax, changed = after.SharingMax, true }
+	if(after.UserGroupName != before.UserGroupName){ request.PlanName, changed = after.UserGroupName, true }
+	if(!changed){ return nil, nil }
+	
+	if(before.UserId != ""){
+		request.UserId = before.UserId
+	}else{
+		request.Code = before.Code
+	}
+	return request, nil
+}
@@ -0,0 +1,92 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+package innGateApi_test
+
+import (
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+//TestModifyAccountUnlimitedLogins sets LoginMax to 0, the gateway's
+//unlimited, which must turn the login limit off rather than fail.
+func TestModifyAccountUnlimitedLogins(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host()
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, LoginMax : innGateApi.MustLogins(3)})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	
+	change, err := innGateApi.ModifyAccount(ant, innGateApi.AccountGetRequest{Code : add.Codes[0]}, func(account *innGateApi.Account) (error){
+		account.LoginMax = 0
+		return nil
+	})
+	if(err != nil){ t.Fatal(err) }
+	if(change.Request == nil || !change.Request.LoginMax.IsUnlimited()){ t.Fatalf("got request %+v, want UnlimitedLogins", change.Request) }
+	if(!change.Before.LoginLimit || change.After.LoginLimit){ t.Errorf("login limit went from %v to %v, want on to off", change.Before.LoginLimit, change.After.LoginLimit) }
+}
+
+//TestModifyAccountSharedMac names an account by a MAC two accounts have been
+//used from: ModifyAccount must refuse rather than pick one.
+func TestModifyAccountSharedMac(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host()
+	
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Type : innGateApi.AccountTypeCode, Count : 2})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	mac := innGateApi.MustParseMac("00:11:25:87:0B:7D")
+	for _, code := range add.Codes{
+		resp, err := ant.AuthLogin(innGateApi.AuthLoginRequest{ClientMac : mac, ClientIp : netip.MustParseAddr("10.0.0.7"), Ppli : "ppli", LocationIndex : 1, Code : code})
+		if(err != nil){ t.Fatal(err) }
+		if err := resp.Err(); err != nil{ t.Fatal(err) }
+	}
+	
+	called := false
+	_, err = innGateApi.ModifyAccount(ant, innGateApi.AccountGetRequest{ClientMac : mac}, func(account *innGateApi.Account) (error){
+		called = true
+		return nil
+	})
+	if(err == nil || called){ t.Errorf("got err %v, modify called %v; want an error before modify", err, called) }
+}
+
+//TestModifyAccountRereadFails fails the read after a successful update: the
+//change must still be returned, with an error saying the update went through.
+func TestModifyAccountRereadFails(t *testing.T){
+	mock := &innGateApi.Mock{}
+	reads := 0
+	mock.AccountGetFunc = func(request innGateApi.AccountGetRequest) (*innGateApi.AccountGetResponse, error){
+		reads++
+		if(reads > 1){ return nil, errors.New("connection reset") }
+		common := innGateApi.ResponseCommon{Op : "account_get", Result : "ok"}
+		return &innGateApi.AccountGetResponse{ResponseCommon : common, Accounts : []innGateApi.Account{{Code : "k2m4p", Description : "lobby"}}}, nil
+	}
+	change, err := innGateApi.ModifyAccount(mock, innGateApi.AccountGetRequest{Code : "k2m4p"}, func(account *innGateApi.Account) (error){
+		account.Description = "pool"
+		return nil
+	})
+	if(!errors.Is(err, innGateApi.ErrUpdateApplied)){ t.Fatalf("got %v, want ErrUpdateApplied", err) }
+	if(change == nil || change.Request == nil || change.Before.Description != "lobby" || change.After.Description != "pool"){ t.Fatalf("got change %+v", change) }
+	if(len(mock.CallsTo("account_update")) != 1){ t.Errorf("account_update was sent %d times, want 1", len(mock.CallsTo("account_update"))) }
+}
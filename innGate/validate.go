@@ -0,0 +1,347 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//ErrInvalidRequest is wrapped by every *ValidationError, for errors.Is.
+var ErrInvalidRequest = errors.New("innGateApi: invalid request")
+
+//FieldError is one problem with one field of a request.  Field is the name of
+//the Go field, e.g. "UserIdLength".
+type FieldError struct{
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() (string){ return e.Field + " " + e.Reason }
+
+//ValidationError is returned by a request's Validate, and by the op before
+//anything is sent, when the request breaks the constraints the API documents.
+//Fields lists every problem found, in the order of the request's fields.
+type ValidationError struct{
+	Op     string
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() (string){
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields{ reasons[i] = f.Error() }
+	return "innGateApi: invalid " + e.Op + " request: " + strings.Join(reasons, "; ") + "."
+}
+
+func (e *ValidationError) Unwrap() (error){ return ErrInvalidRequest }
+
+//validator collects the FieldErrors of one request.
+type validator struct{
+	op     string
+	fields []*FieldError
+}
+
+//check records reason against field unless ok.
+func (v *validator) check(ok bool, field, reason string){
+	if(!ok){ v.fields = append(v.fields, &FieldError{Field : field, Reason : reason}) }
+}
+
+//maxLen checks that s is at most max characters long.
+func (v *validator) maxLen(s string, max int, field string){
+	v.check(utf8.RuneCountInString(s) <= max, field, "is longer than " + strconv.Itoa(max) + " characters")
+}
+
+//minInt checks that n is unset (0) or at least min.
+func (v *validator) minInt(n, min int64, field string){
+	v.check(n == 0 || n >= min, field, "must be at least " + strconv.FormatInt(min, 10))
+}
+
+//format checks that f is one of the Format constants.
+func (v *validator) format(f Format, field string){
+	v.check(formats.valid(f), field, "must be alpha, alnum or num")
+}
+
+//start checks that s is unset, "auto" or a number.
+func (v *validator) start(s, field string){
+	v.check(s == "" || s == "auto" || isDigits(s), field, "must be a number or auto")
+}
+
+//window checks that a start time is not after its end, when both are set.
+func (v *validator) window(start, end time.Time, field string){
+	v.check(start.IsZero() || end.IsZero() || !start.After(end), field, "is before its start")
+}
+
+//mac checks that mac, if given, is a 48-bit MAC address.
+func (v *validator) mac(mac net.HardwareAddr, field string){
+	v.check(mac == nil || len(mac) == 6, field, "must be a 48-bit MAC address")
+}
+
+//loginZone checks that zone, if set, fits the gateway's smallint.
+func (v *validator) loginZone(zone Optional[int64], field string){
+	n := zone.Or(0)
+	v.check(n >= 0 && n <= 32767, field, "must be between 0 and 32767")
+}
+
+//accountType checks that t is one of the AccountType constants.
+func (v *validator) accountType(t AccountType, field string){
+	v.check(accountTypes.valid(t), field, "must be userid or code")
+}
+
+func (v *validator) err() (error){
+	if(len(v.fields) == 0){ return nil }
+	return &ValidationError{Op : v.op, Fields : v.fields}
+}
+
+func isDigits(s string) (bool){
+	if(s == ""){ return false }
+	for _, c := range s{
+		if(c < '0' || c > '9'){ return false }
+	}
+	return true
+}
+
+//given reports whether an AccountDeleteRequest field names any accounts; ok is
+//false if it is neither a string nor a []string.
+func given(value interface{}) (given, ok bool){
+	switch value := value.(type){
+	case nil:      return false, true
+	case string:   return value != "", true
+	case []string: return len(value) > 0, true
+	}
+	return false, false
+}
+
+//isCode reports whether s is an account code: 3 to 10 of a-z and 0-9.
+func isCode(s string) (bool){
+	return len(s) >= 3 && len(s) <= 10 && isCodeChars(s)
+}
+
+//isCodeChars reports whether s is made only of a-z and 0-9, as a code is.
+func isCodeChars(s string) (bool){
+	for _, c := range s{
+		if((c < 'a' || c > 'z') && (c < '0' || c > '9')){ return false }
+	}
+	return true
+}
+
+//isUserId reports whether s is a userid: 3 to 90 of A-Z, a-z, 0-9, -, _ and @.
+func isUserId(s string) (bool){
+	if(len(s) < 3 || len(s) > 90){ return false }
+	for _, c := range s{
+		if((c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '@'){ return false }
+	}
+	return true
+}
+
+//////////////////////////////////////////////////////////
+
+//Validate checks the request without sending it; see ValidationError.
+func (request ModuleRequest) Validate() (error){
+	v := validator{op : "api_module"}
+	v.check(request.Module != "", "Module", "is required")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AuthAuthenticateRequest) Validate() (error){
+	v := validator{op : "auth_authenticate"}
+	v.check(request.Code != "" || request.UserId != "", "Code", "or UserId is required")
+	v.check(request.UserId == "" || request.Password != "", "Password", "is required with UserId")
+	v.check(authModes.valid(request.Mode), "Mode", "must be local or radius")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AuthLoginRequest) Validate() (error){
+	v := validator{op : "auth_login"}
+	if(request.Sid == ""){
+		v.check(request.ClientMac != nil, "ClientMac", "is required without Sid")
+		v.check(request.ClientIp.IsValid(), "ClientIp", "is required without Sid")
+		v.check(request.Ppli != "", "Ppli", "is required without Sid")
+		v.check(request.LocationIndex >= 0, "LocationIndex", "must not be negative")
+	}
+	v.check(loginModes.valid(request.Mode), "Mode", "must be login or relogin")
+	v.check(request.UserId == "" || request.Password != "", "Password", "is required with UserId")
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AuthLogoutRequest) Validate() (error){
+	v := validator{op : "auth_logout"}
+	v.check(request.Sid != "" || request.ClientMac != nil, "Sid", "or ClientMac is required")
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AuthInitRequest) Validate() (error){
+	v := validator{op : "auth_init"}
+	v.check(request.ClientMac != nil, "ClientMac", "is required")
+	v.check(request.ClientIp.IsValid(), "ClientIp", "is required")
+	v.check(request.LocationIndex != "", "LocationIndex", "is required")
+	v.check(request.LocationIndex == "" || isDigits(request.LocationIndex), "LocationIndex", "must be a number")
+	v.check(request.Ppli != "", "Ppli", "is required")
+	_, err := url.ParseQuery(strings.TrimPrefix(request.Extra, "&"))
+	v.check(err == nil, "Extra", "is not a valid query string")
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AuthUpdateRequest) Validate() (error){
+	v := validator{op : "auth_update"}
+	v.check(request.ClientMac != nil, "ClientMac", "is required")
+	v.check(request.Duration.IsSet() || request.Volume.IsSet(), "Duration", "or Volume is required")
+	d, b := request.Duration.Or(0), request.Volume.Or(0)
+	v.check(d >= 0 && (d == UnlimitedDuration || d%time.Minute == 0), "Duration", "must be a whole number of minutes or unlimited")
+	v.check(b >= 0, "Volume", "must not be negative")
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request SidGetRequest) Validate() (error){
+	v := validator{op : "sid_get"}
+	v.check(request.Sid != "", "Sid", "is required")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request PublicIpRequest) Validate() (error){
+	v := validator{op : "publicip_get"}
+	if(request.Sid == ""){
+		v.check(request.ClientMac != nil, "ClientMac", "is required without Sid")
+		v.check(request.Ppli != "", "Ppli", "is required without Sid")
+	}
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+//////////////////////////////////////////////////////////
+
+//Validate checks the request without sending it; see ValidationError.  The
+//plan is checked against the gateway only when the request is sent.
+func (request AccountAddRequest) Validate() (error){
+	v := validator{op : "account_add"}
+	v.check(request.Creator != "", "Creator", "is required")
+	v.maxLen(request.Creator, 20, "Creator")
+	v.check(request.PlanId >= 0, "PlanId", "must be a positive number")
+	v.check(request.PlanId == 0 || request.PlanName == "", "PlanName", "may not be given with PlanId")
+	v.accountType(request.Type, "Type")
+	
+	v.check(request.UserId == "" || isUserId(request.UserId), "UserId", "must be 3 to 90 of A-Z, a-z, 0-9, -, _ and @")
+	v.format(request.UserIdFormat, "UserIdFormat")
+	v.minInt(request.UserIdLength, 3, "UserIdLength")
+	v.maxLen(request.UserIdPrefix, 20, "UserIdPrefix")
+	v.maxLen(request.UserIdSuffix, 20, "UserIdSuffix")
+	v.start(request.UserIdStart, "UserIdStart")
+	
+	v.minInt(request.PasswordLength, 3, "PasswordLength")
+	v.format(request.PasswordFormat, "PasswordFormat")
+	
+	v.check(request.Code == "" || isCode(request.Code), "Code", "must be 3 to 10 of a-z and 0-9")
+	v.format(request.CodeFormat, "CodeFormat")
+	v.minInt(request.CodeLength, 3, "CodeLength")
+	v.check(request.CodeLength <= 10, "CodeLength", "must be at most 10")
+	v.check(isCodeChars(request.CodePrefix), "CodePrefix", "must be of a-z and 0-9")
+	v.check(isCodeChars(request.CodeSuffix), "CodeSuffix", "must be of a-z and 0-9")
+	length := request.CodeLength
+	if(length == 0){ length = 5 }
+	//The generated code is a code, so it must fit in 10 characters.
+	v.check(length > 10 || int64(len(request.CodePrefix) + len(request.CodeSuffix)) + length <= 10, "CodeSuffix", "and CodePrefix leave too few of a code's 10 characters for CodeLength")
+	v.start(request.CodeStart, "CodeStart")
+	
+	v.check(request.Count >= 0 && request.Count <= 100, "Count", "must be between 1 and 100")
+	v.maxLen(request.Description, 255, "Description")
+	validFrom, validUntil := request.ValidFrom.Or(time.Time{}), request.ValidUntil.Or(time.Time{})
+	v.check(!request.ValidFrom.IsSet() || !validFrom.IsZero(), "ValidFrom", "must be a time; leave it unset to start now")
+	v.window(validFrom, validUntil, "ValidUntil")
+	v.check(request.LoginMax.valid(), "LoginMax", "must be unlimited or at least 1")
+	v.minInt(request.SharingMax, 1, "SharingMax") //account_add: "Value >= 1, default 1"
+	v.maxLen(request.BillingId, 100, "BillingId")
+	v.loginZone(request.AllowedLoginZone, "AllowedLoginZone")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AccountGetRequest) Validate() (error){
+	v := validator{op : "account_get"}
+	v.check(request.UserId != "" || request.Code != "" || request.ClientMac != nil, "UserId", "or Code or ClientMac is required")
+	v.mac(request.ClientMac, "ClientMac")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AccountGetAllRequest) Validate() (error){
+	v := validator{op : "account_get_all"}
+	v.window(request.ValidFromStart, request.ValidFromEnd, "ValidFromEnd")
+	v.window(request.ValidUntilStart, request.ValidUntilEnd, "ValidUntilEnd")
+	v.window(request.CreatedStart, request.CreatedEnd, "CreatedEnd")
+	v.accountType(request.Type, "Type")
+	v.check(request.LoginCountMin.Or(0) >= 0, "LoginCountMin", "must not be negative")
+	v.check(request.LoginCountMax.Or(0) >= 0, "LoginCountMax", "must not be negative")
+	v.check(request.LoginCountMax.Or(request.LoginCountMin.Or(0)) >= request.LoginCountMin.Or(0), "LoginCountMax", "is less than LoginCountMin")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AccountDeleteRequest) Validate() (error){
+	v := validator{op : "account_delete"}
+	userId, ok := given(request.UserId)
+	v.check(ok, "UserId", "must be a string or []string")
+	code, ok := given(request.Code)
+	v.check(ok, "Code", "must be a string or []string")
+	v.check(userId || code, "UserId", "or Code is required")
+	return v.err()
+}
+
+//Validate checks the request without sending it; see ValidationError.
+func (request AccountUpdateRequest) Validate() (error){
+	v := validator{op : "account_update"}
+	v.check(request.UserId != "" || request.Code != "", "UserId", "or Code is required")
+	v.minInt(request.PasswordLength, 3, "PasswordLength")
+	v.format(request.PasswordFormat, "PasswordFormat")
+	v.maxLen(request.Description.Or(""), 255, "Description")
+	validFrom, validUntil := request.ValidFrom.Or(time.Time{}), request.ValidUntil.Or(time.Time{})
+	//The API guide's account_update: valid_from is "required if valid_until is
+	//set to a unix time".  Removing valid_until (NoTime) needs no valid_from.
+	v.check(validUntil.IsZero() || !validFrom.IsZero(), "ValidFrom", "is required with ValidUntil")
+	v.window(validFrom, validUntil, "ValidUntil")
+	v.check(request.LoginMax.valid(), "LoginMax", "must be unlimited or at least 1")
+	v.check(!request.LoginMax.IsUnlimited() || !request.LoginLimit.Or(false), "LoginMax", "may not be unlimited with LoginLimit")
+	v.minInt(request.SharingMax, 2, "SharingMax") //account_update: "value >= 2 and bigger than previous value"
+	v.loginZone(request.AllowedLoginZone, "AllowedLoginZone")
+	v.check(request.PlanId >= 0, "PlanId", "must be a positive number")
+	v.check(request.PlanId == 0 || request.PlanName == "", "PlanName", "may not be given with PlanId")
+	return v.err()
+}
+//////////////////////////////////////////////////////////
+
+//Validate checks the request without sending it; see ValidationError.
+func (request PlanIdRequest) Validate() (error){
+	v := validator{op : "plan_get_id"}
+	v.check(request.Name != "", "Name", "is required")
+	return v.err()
+}
@@ -0,0 +1,91 @@
+//  Copyright 2012 ChaseFox (Matthew R Chase)
+//  
+//  This file is part of gantlabs, a go library for communicating with
+//  ANTLabs devices. http://www.antlabs.com/
+//  
+//  gantlabs is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU General Public License as published
+//  by the Free Software Foundation, either version 3 of the License,
+//  or (at your option) any later version.
+//  
+//  gantlabs is distributed in the hope that it will be useful, but
+//  WITHOUT ANY WARRANTY; without even the implied warranty of 
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU General Public License for more details.
+//  
+//  You should have received a copy of the GNU General Public License
+//  along with gantlabs.  If not, see <http://www.gnu.org/licenses/>.
+
+
+package innGateApi_test
+
+import (
+	"errors"
+	"github.com/secesh/gantlabs/innGate"
+	"github.com/secesh/gantlabs/innGate/innGateTest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//TestValidateBeforeSending checks that requests breaking the API's limits
+//fail with a *ValidationError naming the field, and never reach the fake.
+func TestValidateBeforeSending(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	metrics := innGateApi.NewMemoryMetrics()
+	ant := gw.Host(innGateApi.WithMetrics(metrics))
+	
+	until := innGateApi.Set(time.Now().Add(time.Hour))
+	for _, c := range []struct{
+		field string
+		send  func() (error)
+	}{
+		{"Code", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Code : "AB"}); return err }},
+		{"CodeLength", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", CodeLength : 11}); return err }},
+		{"CodePrefix", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", CodePrefix : "AB"}); return err }},
+		{"CodeSuffix", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", CodeSuffix : "x-y"}); return err }},
+		{"CodeSuffix", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", CodePrefix : "lobby", CodeSuffix : "x"}); return err }},
+		{"Count", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Count : 101}); return err }},
+		{"SharingMax", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", SharingMax : -1}); return err }},
+		{"SharingMax", func() (error){ _, err := ant.AccountUpdate(innGateApi.AccountUpdateRequest{Code : "k2m4p", SharingMax : 1}); return err }},
+		{"ValidFrom", func() (error){ _, err := ant.AccountUpdate(innGateApi.AccountUpdateRequest{Code : "k2m4p", ValidUntil : until}); return err }},
+		{"ValidFrom", func() (error){ _, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", ValidFrom : innGateApi.NoTime()}); return err }},
+	}{
+		err := c.send()
+		var verr *innGateApi.ValidationError
+		if(!errors.As(err, &verr) || !errors.Is(err, innGateApi.ErrInvalidRequest)){ t.Errorf("%s: got %v, want a *ValidationError", c.field, err); continue }
+		if(len(verr.Fields) != 1 || verr.Fields[0].Field != c.field){ t.Errorf("got %v, want only %s", err, c.field) }
+	}
+	if n := len(metrics.Snapshot()); n != 0{ t.Errorf("%d ops reached the gateway", n) }
+}
+
+//TestValidUntilNeedsValidFrom checks that the fake, like the gateway,
+//refuses an account_update that sets valid_until without valid_from, and
+//that ModifyAccount sends both.
+func TestValidUntilNeedsValidFrom(t *testing.T){
+	gw := innGateTest.NewServer()
+	defer gw.Close()
+	ant := gw.Host()
+	add, err := ant.AccountAdd(innGateApi.AccountAddRequest{Creator : "admin", Code : "k2m4p"})
+	if(err != nil){ t.Fatal(err) }
+	if err := add.Err(); err != nil{ t.Fatal(err) }
+	
+	query := url.Values{"api_password" : {gw.Password}, "op" : {"account_update"}, "code" : {"k2m4p"},
+		"valid_until" : {strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)}}
+	resp, err := gw.Client().Get(gw.URL + "/api/?" + query.Encode())
+	if(err != nil){ t.Fatal(err) }
+	resp.Body.Close()
+	get, err := ant.AccountGet(innGateApi.AccountGetRequest{Code : "k2m4p"})
+	if(err != nil){ t.Fatal(err) }
+	if(!get.Accounts[0].ValidUntil.IsZero()){ t.Errorf("the fake set valid_until without valid_from") }
+	
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	change, err := innGateApi.ModifyAccount(ant, innGateApi.AccountGetRequest{Code : "k2m4p"}, func(account *innGateApi.Account) (error){
+		account.ValidUntil = until
+		return nil
+	})
+	if(err != nil){ t.Fatal(err) }
+	if(!change.After.ValidUntil.Equal(until)){ t.Errorf("got valid_until %v, want %v", change.After.ValidUntil, until) }
+}